@@ -2,28 +2,66 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 	"visualizer-go/internal/handler"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/bruteforce"
 	"visualizer-go/internal/lib/config"
 	"visualizer-go/internal/lib/db/postgres"
+	"visualizer-go/internal/lib/mailer"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/secretbox"
 	"visualizer-go/internal/lib/server"
+	"visualizer-go/internal/lib/tracing"
 	"visualizer-go/internal/repository"
 	"visualizer-go/internal/service"
 )
 
-const (
-	envLocal = "local"
-	envDev   = "dev"
-	envProd  = "prod"
-)
-
 func main() {
-	cfg := config.MustLoad()
+	configPath := flag.String("config", "", "path to the config file (default $CONFIG_PATH or ./configs/$APP_ENV.yaml)")
+	flag.Usage = config.Usage(flag.CommandLine.Output(), func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-config path] [config print]\n", os.Args[0])
+		flag.PrintDefaults()
+	})
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch args := flag.Args(); {
+	case len(args) == 0:
+	case len(args) == 2 && args[0] == "config" && args[1] == "print":
+		// Printing comes first so an invalid config can still be inspected.
+		if err = cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err = cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err = cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
 
 	log := setupLogger(cfg.Env)
 	log = log.With(slog.String("env", cfg.Env))
@@ -31,19 +69,77 @@ func main() {
 	log.Info("initializing server...", slog.String("address", cfg.Server.Host+":"+cfg.Server.Port))
 	log.Debug("logger debug mode enabled")
 
-	db := postgres.MustConnect(log, cfg.Database)
-	repo := repository.New(log, db)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, metrics.Version)
+	if err != nil {
+		log.Error("failed to set up tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	db, err := postgres.Connect(context.Background(), log, cfg.Database)
+	if err != nil {
+		log.Error("failed to connect to database", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	replicaDB := setupReplica(log, cfg.Replica)
+	// Write markers are signed with the token secret every instance shares.
+	repo := repository.New(log, db, replicaDB, cfg.Replica.StickyWindow, cfg.Jwt.Secret)
+	tokens := auth.NewTokenManager(cfg.Jwt.Secret, cfg.Jwt.TTL, cfg.TwoFactor.ChallengeTTL)
 	svc := service.New(log, service.Deps{
-		Repo: repo,
+		Repo:          repo,
+		Tokens:        tokens,
+		Mailer:        mailer.MustNew(log, cfg.Mail),
+		Origin:        cfg.Origin,
+		InvitationTTL: cfg.Invitations.TTL,
+		PasswordReset: cfg.PasswordReset,
+		LoginGuard:    bruteforce.NewGuard(setupLoginAttemptStore(log, cfg.LoginProtection, db), cfg.LoginProtection),
+		SecretBox:     secretbox.MustNew(cfg.TwoFactor.EncryptionKey),
+		TOTPIssuer:    cfg.TwoFactor.Issuer,
+		OIDC:          cfg.OIDC,
+		APIKeys:       cfg.APIKeys,
+		Embed:         cfg.Embed,
+		Scheduler:     cfg.Scheduler,
+		Analytics:     cfg.Analytics,
+		ViewCounter:   cfg.ViewCounter,
+		Health:        cfg.Health,
+		ShareCache:    cfg.ShareCache,
 	})
-	h := handler.New(log, svc, cfg.Origin)
+	metricsSrv := setupMetrics(log, cfg.Server, cfg.Database, db, replicaDB, repo, svc)
+
+	// With a metrics port of its own, /metrics stays off the public port.
+	metricsToken := cfg.Server.MetricsToken
+	if metricsSrv != nil {
+		metricsToken = ""
+	}
+
+	h := handler.New(log, svc, cfg.Origin, metricsToken, repo.Reads, cfg.ShareCache, cfg.Server.TrustedProxies)
 
 	srv := server.New(log, cfg.Server, h.Init())
+	srv.RegisterOnStop(svc.Health.Drain)
 
 	go func() {
 		srv.MustRun()
 	}()
 
+	if metricsSrv != nil {
+		go func() {
+			metricsSrv.MustRun()
+		}()
+	}
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		svc.Scheduler.Run(schedulerCtx)
+	}()
+
+	viewCounterCtx, stopViewCounter := context.WithCancel(context.Background())
+	viewCounterDone := make(chan struct{})
+	go func() {
+		defer close(viewCounterDone)
+		svc.ViewCounter.Run(viewCounterCtx)
+	}()
+
 	// Graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
@@ -54,33 +150,123 @@ func main() {
 
 	const timeout = 5 * time.Second
 
-	ctx, shutdown := context.WithTimeout(context.Background(), timeout)
+	ctx, shutdown := context.WithTimeout(context.Background(), timeout+cfg.Server.DrainDelay)
 	defer shutdown()
 
 	if err := srv.Stop(ctx); err != nil {
-		log.Error("error occurred while stopping http server: %s", err.Error())
+		log.Error("error occurred while stopping http server", slog.String("error", err.Error()))
+	}
+
+	if metricsSrv != nil {
+		if err := metricsSrv.Stop(ctx); err != nil {
+			log.Error("error occurred while stopping metrics server", slog.String("error", err.Error()))
+		}
 	}
 
 	log.Info("server successfully stopped")
 
+	// Let an in-flight batch finish before the database goes away.
+	stopScheduler()
+	<-schedulerDone
+
+	log.Info("scheduler successfully stopped")
+
+	// Requests have drained, so this flush writes every buffered view.
+	stopViewCounter()
+	<-viewCounterDone
+
+	log.Info("view counter successfully stopped")
+
 	if err := db.Close(); err != nil {
-		log.Error("error occurred while closing database: %s", err.Error())
+		log.Error("error occurred while closing database", slog.String("error", err.Error()))
+	}
+
+	if replicaDB != nil {
+		if err := replicaDB.Close(); err != nil {
+			log.Error("error occurred while closing replica", slog.String("error", err.Error()))
+		}
 	}
 
 	log.Info("postgres successfully closed")
 
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error("error occurred while flushing traces", slog.String("error", err.Error()))
+	}
+
 	log.Info("application gracefully stopped")
 }
 
+// setupMetrics registers the collectors that need runtime state and returns
+// the server for the metrics port, or nil when metrics share the main port.
+func setupMetrics(log *slog.Logger, cfg config.Server, dbCfg config.Database, db, replicaDB *sqlx.DB, repo *repository.Repository, svc *service.Service) *server.Server {
+	const entityCountTimeout = 5 * time.Second
+
+	metrics.MustRegister(
+		metrics.DBStats(db.DB, dbCfg.DBName),
+		metrics.NewEntityCollector(log, entityCountTimeout, func(ctx context.Context) (metrics.EntityCounts, error) {
+			counts, err := repo.CountEntities(ctx)
+			return metrics.EntityCounts(counts), err
+		}),
+		metrics.GaugeFunc("view_counter_pending_views", "Views buffered in memory and not yet written.", func() float64 {
+			return float64(svc.ViewCounter.Stats().Pending)
+		}),
+	)
+
+	if replicaDB != nil {
+		metrics.MustRegister(metrics.DBStats(replicaDB.DB, dbCfg.DBName+"-replica"))
+	}
+
+	if cfg.MetricsPort == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	// Only the API port is behind the load balancer, so there is nothing
+	// to drain here and scrapes keep working until the end.
+	cfg.Port = cfg.MetricsPort
+	cfg.DrainDelay = 0
+	return server.New(log, cfg, mux)
+}
+
+// setupReplica opens the read replica pool, or returns nil when none is
+// configured. Startup does not wait for the replica: reads fall back to the
+// primary while it is unreachable.
+func setupReplica(log *slog.Logger, cfg config.Replica) *sqlx.DB {
+	if cfg.Host == "" {
+		return nil
+	}
+
+	db, err := postgres.Open(cfg.Database)
+	if err != nil {
+		log.Error("failed to open replica", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	log.Info("read replica configured", slog.String("host", cfg.Host), slog.Duration("stickyWindow", cfg.StickyWindow))
+
+	return db
+}
+
+func setupLoginAttemptStore(log *slog.Logger, cfg config.LoginProtection, db *sqlx.DB) bruteforce.Store {
+	switch cfg.Store {
+	case "postgres":
+		return repository.NewLoginAttemptRepo(log, db)
+	default:
+		return bruteforce.NewMemoryStore()
+	}
+}
+
 func setupLogger(env string) *slog.Logger {
 	var log *slog.Logger
 
 	switch env {
-	case envLocal:
+	case config.EnvLocal:
 		log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	case envDev:
+	case config.EnvDev:
 		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	case envProd:
+	case config.EnvProd:
 		log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	}
 
@@ -0,0 +1,9 @@
+package dto
+
+import "time"
+
+type AnalyticsQuery struct {
+	From     *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Interval string     `form:"interval,default=day" binding:"oneof=hour day week month"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+import "time"
+
+type APIKeyCreateDto struct {
+	Name      string     `json:"name" db:"name" binding:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" db:"scopes" binding:"required,min=1,dive,oneof=read write publish"`
+	ExpiresAt *time.Time `json:"expiresAt" db:"expires_at"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+type AuditQuery struct {
+	ActorID    string     `form:"actorId" binding:"omitempty,uuid"`
+	OrgID      string     `form:"orgId" binding:"omitempty,uuid"`
+	Action     string     `form:"action" binding:"omitempty,max=64"`
+	EntityType string     `form:"entityType" binding:"omitempty,max=32"`
+	EntityID   string     `form:"entityId" binding:"omitempty,max=64"`
+	From       *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page       int        `form:"page,default=1" binding:"min=1"`
+	Limit      int        `form:"limit,default=50" binding:"min=1,max=500"`
+}
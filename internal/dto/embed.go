@@ -0,0 +1,8 @@
+package dto
+
+type EmbedTokenCreateDto struct {
+	// ExpiresIn is the token lifetime in seconds; zero uses the configured default.
+	ExpiresIn      int               `json:"expiresIn" binding:"omitempty,min=60"`
+	Params         map[string]string `json:"params" binding:"omitempty,max=20,dive,keys,min=1,max=64,endkeys,max=256"`
+	AllowedOrigins []string          `json:"allowedOrigins" binding:"omitempty,max=10,dive,required,max=255"`
+}
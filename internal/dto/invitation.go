@@ -0,0 +1,14 @@
+package dto
+
+type InvitationCreateDto struct {
+	Email string `json:"email" db:"email" binding:"required,email,max=255"`
+	Role  string `json:"role" db:"role" binding:"required,oneof=admin editor viewer"`
+}
+
+// InvitationAcceptDto redeems an invitation. Username and Password create
+// the account and are ignored when the invited email already has one.
+type InvitationAcceptDto struct {
+	Token    string `json:"token" binding:"required,max=128"`
+	Username string `json:"username" db:"username" binding:"omitempty,min=3,max=64"`
+	Password string `json:"password" binding:"omitempty,min=8,max=72"`
+}
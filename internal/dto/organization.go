@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+)
+
+type OrganizationCreateDto struct {
+	Name string `json:"name" db:"name" binding:"required,min=1,max=255"`
+	Slug string `json:"slug" db:"slug" binding:"required,min=2,max=64,slug"`
+}
+
+type OrganizationUpdateDto struct {
+	RequireAdminTwoFactor *bool `json:"requireAdminTwoFactor" db:"require_admin_two_factor" binding:"required"`
+}
+
+type OrganizationMemberCreateDto struct {
+	UserID uuid.UUID `json:"userId" db:"user_id" binding:"required"`
+	Role   string    `json:"role" db:"role" binding:"required,oneof=admin editor viewer"`
+}
+
+type OrganizationMemberUpdateDto struct {
+	Role string `json:"role" db:"role" binding:"required,oneof=admin editor viewer"`
+}
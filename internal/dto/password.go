@@ -0,0 +1,10 @@
+package dto
+
+type PasswordForgotDto struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+
+type PasswordResetDto struct {
+	Token    string `json:"token" binding:"required,max=128"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+type ShareLinkCreateDto struct {
+	Name      string     `json:"name" binding:"required,min=1,max=100"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Password  string     `json:"password" binding:"omitempty,min=4,max=72"`
+}
+
+type ShareLinkUnlockDto struct {
+	Password string `json:"password" binding:"required,max=72"`
+}
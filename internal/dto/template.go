@@ -1,14 +1,14 @@
 package dto
 
 type TemplateCreateDto struct {
-	Name        string       `json:"name" db:"name"`
-	Description *string      `json:"description" db:"description"`
-	Canvases    *interface{} `json:"canvases" db:"canvases"`
+	Name        string       `json:"name" db:"name" binding:"required,min=1,max=255"`
+	Description *string      `json:"description" db:"description" binding:"omitempty,max=2000"`
+	Canvases    *interface{} `json:"canvases" db:"canvases" binding:"omitempty,maxjsonbytes=5242880"`
 }
 
 type TemplateUpdateDto struct {
-	Name        *string      `json:"name" db:"name"`
-	Description *string      `json:"description" db:"description"`
-	Canvases    *interface{} `json:"canvases" db:"canvases"`
+	Name        *string      `json:"name" db:"name" binding:"omitempty,min=1,max=255"`
+	Description *string      `json:"description" db:"description" binding:"omitempty,max=2000"`
+	Canvases    *interface{} `json:"canvases" db:"canvases" binding:"omitempty,maxjsonbytes=5242880"`
 	IsDeleted   *bool        `json:"isDeleted" db:"is_deleted"`
 }
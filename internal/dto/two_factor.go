@@ -0,0 +1,17 @@
+package dto
+
+// TwoFactorCodeDto carries either a code from the authenticator app or one
+// of the recovery codes handed out on enrollment.
+type TwoFactorCodeDto struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recoveryCode" binding:"omitempty,max=32"`
+}
+
+type TwoFactorConfirmDto struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type TwoFactorVerifyDto struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	TwoFactorCodeDto
+}
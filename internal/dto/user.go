@@ -1,15 +1,40 @@
 package dto
 
+import "github.com/google/uuid"
+
 type UserCreateDto struct {
-	Username string `json:"username" db:"username"`
-	Password string `json:"password" db:"password_hash"`
+	Username string  `json:"username" db:"username" binding:"required,min=3,max=64"`
+	Email    *string `json:"email" db:"email" binding:"omitempty,email,max=255"`
+	Password string  `json:"password" db:"password_hash" binding:"required,min=8,max=72"`
 }
 
 type UserLoginDto struct {
-	Username string `json:"username" db:"username"`
-	Password string `json:"password" db:"password_hash"`
+	Username string `json:"username" db:"username" binding:"required,max=64"`
+	Password string `json:"password" db:"password_hash" binding:"required,max=72"`
 }
 
 type UserUpdateDto struct {
-	Role *string `json:"role" db:"role"`
+	Role *string `json:"role" db:"role" binding:"omitempty,oneof=admin editor viewer"`
+}
+
+type UserListQuery struct {
+	Search string `form:"search" binding:"omitempty,max=64"`
+	Page   int    `form:"page,default=1" binding:"min=1"`
+	Limit  int    `form:"limit,default=10" binding:"min=1,max=100"`
+}
+
+type UserDeleteQuery struct {
+	ReassignTo string `form:"reassignTo" binding:"omitempty,uuid"`
+}
+
+// OIDCUserDto describes a user provisioned on first single sign-on.
+type OIDCUserDto struct {
+	Username     string  `db:"username"`
+	Email        *string `db:"email"`
+	PasswordHash string  `db:"password_hash"`
+	Role         string  `db:"role"`
+	Issuer       string  `db:"oidc_issuer"`
+	Subject      string  `db:"oidc_subject"`
+	// OrgID, when set, is the organization the user joins with Role.
+	OrgID uuid.UUID `db:"-"`
 }
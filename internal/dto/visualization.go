@@ -1,23 +1,43 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type VisualizationCreateDto struct {
-	Name       string       `json:"name" db:"name"`
-	Canvases   *interface{} `json:"canvases" db:"canvases"`
-	TemplateID *uuid.UUID   `json:"templateId" db:"template_id"`
-	UserID     uuid.UUID    `json:"userId" db:"user_id"`
+	Name       string       `json:"name" db:"name" binding:"required,min=1,max=255"`
+	Canvases   *interface{} `json:"canvases" db:"canvases" binding:"omitempty,maxjsonbytes=5242880"`
+	TemplateID *uuid.UUID   `json:"templateId" db:"template_id" binding:"omitnil,uuid"`
+	// UserID is filled from the authenticated user, never from the request body.
+	UserID uuid.UUID `json:"-" db:"user_id"`
 }
 
 type VisualizationUpdateDto struct {
-	Name        *string      `json:"name" db:"name"`
-	Description *string      `json:"description" db:"description"`
-	Client      *string      `json:"client" db:"client"`
-	IsPublished *bool        `json:"published" db:"is_published"`
-	Canvases    *interface{} `json:"canvases" db:"canvases"`
-	TemplateID  *uuid.UUID   `json:"templateId" db:"template_id"`
-	Tenant      *string      `json:"tenant" db:"tenant"`
-	ViewCount   *uint        `json:"viewCount" db:"view_count"`
+	Name        *string      `json:"name" db:"name" binding:"omitempty,min=1,max=255"`
+	Description *string      `json:"description" db:"description" binding:"omitempty,max=2000"`
+	Client      *string      `json:"client" db:"client" binding:"omitempty,max=255"`
+	Canvases    *interface{} `json:"canvases" db:"canvases" binding:"omitempty,maxjsonbytes=5242880"`
+	TemplateID  *uuid.UUID   `json:"templateId" db:"template_id" binding:"omitnil,uuid"`
+	Tenant      *string      `json:"tenant" db:"tenant" binding:"omitempty,max=255"`
+}
+
+type VisualizationSubmitDto struct {
+	Reviewers []uuid.UUID `json:"reviewers" binding:"required,min=1,max=10"`
+}
+
+type VisualizationReviewDto struct {
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+type VisualizationRejectDto struct {
+	Comment string `json:"comment" binding:"required,min=1,max=2000"`
+}
+
+// VisualizationScheduleDto replaces the publishing schedule. A nil time
+// clears that part of the schedule.
+type VisualizationScheduleDto struct {
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
 }
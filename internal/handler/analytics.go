@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrAnalyticsInvalidRequestData = errors.New("invalid analytics request data")
+
+func (h *Handler) getVisualizationAnalytics(c *gin.Context) {
+	const op = "handler.Handler.getVisualizationAnalytics"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	var query dto.AnalyticsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrAnalyticsInvalidRequestData.Error(), err)
+		return
+	}
+
+	analytics, err := h.services.Analytics.Get(c.Request.Context(), visualizationID, query)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, service.ErrAnalyticsRangeInvalid):
+			response.Error(c, http.StatusBadRequest, service.ErrAnalyticsRangeInvalid.Error(), nil)
+		case errors.Is(err, service.ErrAnalyticsRangeTooLarge):
+			response.Error(c, http.StatusBadRequest, service.ErrAnalyticsRangeTooLarge.Error(), nil)
+		case errors.Is(err, repository.ErrVisualizationNotFound):
+			response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, repository.ErrFailedToFetchAnalytics.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Analytics fetched successfully", analytics)
+}
+
+// viewerFromRequest collects what analytics needs to know about the client.
+func viewerFromRequest(c *gin.Context) service.Viewer {
+	return service.Viewer{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Referrer:  c.Request.Referer(),
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAPIKeyInvalidRequestData = errors.New("invalid API key request data")
+	ErrInvalidAPIKeyID          = errors.New("invalid API key ID format")
+)
+
+func (h *Handler) getAPIKeys(c *gin.Context) {
+	const op = "handler.Handler.getAPIKeys"
+
+	keys, err := h.services.APIKey.GetAll(c.Request.Context())
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusInternalServerError, repository.ErrFailedToFetchAPIKeys.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API keys fetched successfully", keys)
+}
+
+func (h *Handler) createAPIKey(c *gin.Context) {
+	const op = "handler.Handler.createAPIKey"
+
+	var apiKeyCreateDto dto.APIKeyCreateDto
+	if err := c.ShouldBindJSON(&apiKeyCreateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrAPIKeyInvalidRequestData.Error(), err)
+		return
+	}
+
+	key, secret, err := h.services.APIKey.Create(c.Request.Context(), apiKeyCreateDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, service.ErrAPIKeyExpiryInPast):
+			response.Error(c, http.StatusBadRequest, service.ErrAPIKeyExpiryInPast.Error(), nil)
+		case errors.Is(err, service.ErrAPIKeyExpiryTooLate):
+			response.Error(c, http.StatusBadRequest, service.ErrAPIKeyExpiryTooLate.Error(), nil)
+		case errors.Is(err, repository.ErrNoActiveOrganization):
+			response.Error(c, http.StatusForbidden, repository.ErrNoActiveOrganization.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, repository.ErrFailedToCreateAPIKey.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "API key created successfully; store it now, it will not be shown again", gin.H{
+		"apiKey": key,
+		"secret": secret,
+	})
+}
+
+func (h *Handler) revokeAPIKey(c *gin.Context) {
+	const op = "handler.Handler.revokeAPIKey"
+
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidAPIKeyID.Error(), nil)
+		return
+	}
+
+	if err = h.services.APIKey.Revoke(c.Request.Context(), keyID); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			response.Error(c, http.StatusNotFound, repository.ErrAPIKeyNotFound.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, repository.ErrFailedToRevokeAPIKey.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "API key revoked successfully", nil)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrAuditInvalidRequestData = errors.New("invalid audit log query")
+
+var auditCSVHeader = []string{
+	"createdAt", "actorId", "actorName", "action", "entityType", "entityId",
+	"orgId", "ip", "requestId", "before", "after",
+}
+
+func (h *Handler) getAuditLog(c *gin.Context) {
+	const op = "handler.Handler.getAuditLog"
+
+	var query dto.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrAuditInvalidRequestData.Error(), err)
+		return
+	}
+
+	entries, rowCount, err := h.services.Audit.GetAll(c.Request.Context(), query)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, service.ErrAuditForbidden) {
+			response.Error(c, http.StatusForbidden, service.ErrAuditForbidden.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, repository.ErrFailedToFetchAudit.Error(), nil)
+		return
+	}
+
+	pageCount := int(math.Ceil(float64(rowCount) / float64(query.Limit)))
+
+	response.Success(c, http.StatusOK, "Audit log fetched successfully", gin.H{
+		"entries": entries,
+		"pagination": gin.H{
+			"rowCount":    rowCount,
+			"pageCount":   pageCount,
+			"currentPage": query.Page,
+			"limit":       query.Limit,
+			"hasMore":     query.Page < pageCount,
+		}})
+}
+
+// exportAuditLog streams the filtered audit log as CSV. Paging parameters
+// are ignored; the export is capped by the repository instead.
+func (h *Handler) exportAuditLog(c *gin.Context) {
+	const op = "handler.Handler.exportAuditLog"
+
+	var query dto.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrAuditInvalidRequestData.Error(), err)
+		return
+	}
+
+	w := csv.NewWriter(c.Writer)
+	started := false
+
+	err := h.services.Audit.Export(c.Request.Context(), query, func(entry models.AuditEntry) error {
+		if !started {
+			started = true
+			writeAuditCSVHeader(c)
+			if err := w.Write(auditCSVHeader); err != nil {
+				return err
+			}
+		}
+		return w.Write(auditCSVRow(entry))
+	})
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		// Once rows have been written the status is already sent; the
+		// truncated file is all the client gets.
+		if started {
+			return
+		}
+		if errors.Is(err, service.ErrAuditForbidden) {
+			response.Error(c, http.StatusForbidden, service.ErrAuditForbidden.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, repository.ErrFailedToFetchAudit.Error(), nil)
+		return
+	}
+
+	if !started {
+		writeAuditCSVHeader(c)
+		_ = w.Write(auditCSVHeader)
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+	}
+}
+
+func writeAuditCSVHeader(c *gin.Context) {
+	filename := fmt.Sprintf("audit-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+}
+
+func auditCSVRow(entry models.AuditEntry) []string {
+	row := []string{
+		entry.CreatedAt.UTC().Format(time.RFC3339),
+		"", deref(entry.ActorName), entry.Action, entry.EntityType, deref(entry.EntityID),
+		"", deref(entry.IP), deref(entry.RequestID), "", "",
+	}
+	if entry.ActorID != nil {
+		row[1] = entry.ActorID.String()
+	}
+	if entry.OrgID != nil {
+		row[6] = entry.OrgID.String()
+	}
+	if entry.Before != nil {
+		row[9] = entry.Before.String()
+	}
+	if entry.After != nil {
+		row[10] = entry.After.String()
+	}
+	for i, cell := range row {
+		row[i] = csvSafe(cell)
+	}
+	return row
+}
+
+// csvSafe neutralises cells that spreadsheet software would evaluate as a
+// formula, since actor names and entity IDs can be user-controlled.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
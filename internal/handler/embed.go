@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmbedInvalidRequestData = errors.New("invalid embed token request data")
+	ErrFailedToIssueEmbedToken = errors.New("failed to issue embed token")
+)
+
+func (h *Handler) createEmbedToken(c *gin.Context) {
+	const op = "handler.Handler.createEmbedToken"
+
+	visualizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), nil)
+		return
+	}
+
+	var embedTokenCreateDto dto.EmbedTokenCreateDto
+	if err = c.ShouldBindJSON(&embedTokenCreateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrEmbedInvalidRequestData.Error(), err)
+		return
+	}
+
+	token, err := h.services.Embed.Issue(c.Request.Context(), visualizationID, embedTokenCreateDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, service.ErrEmbedTTLTooLong):
+			response.Error(c, http.StatusBadRequest, service.ErrEmbedTTLTooLong.Error(), nil)
+		case errors.Is(err, service.ErrEmbedOriginInvalid):
+			response.Error(c, http.StatusBadRequest, service.ErrEmbedOriginInvalid.Error(), nil)
+		case errors.Is(err, service.ErrEmbedNotPublished):
+			response.Error(c, http.StatusConflict, service.ErrEmbedNotPublished.Error(), nil)
+		case errors.Is(err, service.ErrEmbedTokenNotAllowed):
+			response.Error(c, http.StatusForbidden, service.ErrEmbedTokenNotAllowed.Error(), nil)
+		case errors.Is(err, auth.ErrInsufficientScope):
+			response.Error(c, http.StatusForbidden, auth.ErrInsufficientScope.Error(), nil)
+		case errors.Is(err, repository.ErrVisualizationNotFound):
+			response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, ErrFailedToIssueEmbedToken.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Embed token issued successfully", token)
+}
+
+// getEmbed serves the visualization behind an embed token. The policy on
+// this JSON response only guards documents rendered from it; the page that
+// embeds the visualization must apply allowedOrigins itself.
+func (h *Handler) getEmbed(c *gin.Context) {
+	const op = "handler.Handler.getEmbed"
+
+	view, err := h.services.Embed.Open(c.Request.Context(), c.Param("token"), viewerFromRequest(c))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, service.ErrEmbedTokenInvalid):
+			response.Error(c, http.StatusUnauthorized, service.ErrEmbedTokenInvalid.Error(), nil)
+		case errors.Is(err, repository.ErrVisualizationNotFound):
+			response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, ErrFailedToFetchVisualizations.Error(), nil)
+		}
+		return
+	}
+
+	c.Header("Content-Security-Policy", frameAncestors(view.AllowedOrigins))
+	c.Header("Cache-Control", "no-store")
+
+	response.Success(c, http.StatusOK, "Embed fetched successfully", view)
+}
+
+// frameAncestors forbids framing when the token names no origins.
+func frameAncestors(origins []string) string {
+	if len(origins) == 0 {
+		return "frame-ancestors 'none'"
+	}
+	return "frame-ancestors " + strings.Join(origins, " ")
+}
@@ -1,82 +1,197 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
-	"net/http"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/validation"
 	"visualizer-go/internal/middlewares"
+	"visualizer-go/internal/models"
 	"visualizer-go/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+var ErrUnauthenticated = errors.New("unauthenticated")
+
 type Handler struct {
-	log      *slog.Logger
-	services *service.Service
-	origin   string
+	log            *slog.Logger
+	services       *service.Service
+	origin         string
+	metricsToken   string
+	writes         middlewares.WriteTracker
+	shareCache     config.ShareCache
+	trustedProxies []string
 }
 
-func New(log *slog.Logger, service *service.Service, origin string) *Handler {
+func New(log *slog.Logger, service *service.Service, origin, metricsToken string, writes middlewares.WriteTracker, shareCache config.ShareCache, trustedProxies []string) *Handler {
 	return &Handler{
-		log:      log,
-		services: service,
-		origin:   origin,
+		log:            log,
+		services:       service,
+		origin:         origin,
+		metricsToken:   metricsToken,
+		writes:         writes,
+		shareCache:     shareCache,
+		trustedProxies: trustedProxies,
 	}
 }
 
+// requestLog returns the logger of the request, tagged with its ID, route
+// and user.
+func (h *Handler) requestLog(c *gin.Context) *slog.Logger {
+	return logger.FromContext(c.Request.Context(), h.log)
+}
+
 func (h *Handler) Init() *gin.Engine {
+	validation.MustRegister()
+
 	handler := gin.New()
 
-	handler.Use(gin.Recovery(), gin.Logger(), middlewares.CorsMiddleware(h.origin))
+	// gin trusts every proxy by default, which lets any client pick the IP
+	// that rate limits and audit records see. The list is validated at
+	// startup, so an error here is a programming mistake.
+	if err := handler.SetTrustedProxies(h.trustedProxies); err != nil {
+		panic(err)
+	}
+
+	handler.Use(middlewares.MetricsMiddleware(), middlewares.TracingMiddleware(), middlewares.RequestIDMiddleware(h.log), middlewares.AccessLogMiddleware(h.log, "/healthz", "/readyz", "/api/status"), gin.Recovery(), middlewares.ClientIPMiddleware(), middlewares.ReadYourWritesMiddleware(h.writes), middlewares.CorsMiddleware(h.origin))
+
+	// get /healthz and /readyz for load balancers and orchestrators
+	handler.GET("/healthz", h.liveness)
+	handler.GET("/readyz", h.readiness)
+
+	// get /metrics; only used when metrics have no port of their own
+	handler.GET("/metrics", middlewares.RequireMetricsToken(h.metricsToken), gin.WrapH(metrics.Handler()))
 
 	// define group route /api
 	api := handler.Group("/api")
 	{
-		// get /api/status
-		api.GET("/status", func(ctx *gin.Context) {
-			ctx.String(http.StatusOK, "ok")
-		})
+		// get /api/status; an older name for /readyz
+		api.GET("/status", h.readiness)
 
 		// define group route /api/auth
-		auth := api.Group("/auth")
+		authRoutes := api.Group("/auth")
 		{
-			auth.POST("/login", h.login)
+			authRoutes.POST("/login", h.login)
+			authRoutes.POST("/2fa", h.verifyTwoFactor)
+			authRoutes.GET("/oidc/login", h.oidcLogin)
+			authRoutes.GET("/oidc/callback", h.oidcCallback)
+			authRoutes.POST("/forgot", h.forgotPassword)
+			authRoutes.POST("/reset", h.resetPassword)
 		}
 
+		// post /api/invitations/accept
+		api.POST("/invitations/accept", h.acceptInvitation)
+
 		// get /api/visualizations/share/:id
 		api.GET("/visualizations/share/:id", h.getVisualizationByShareID)
+		api.POST("/visualizations/share/:id/unlock", h.unlockShareLink)
 		api.PATCH("visualizations/:id/metric", h.metric)
 
+		// get /api/embed/:token
+		api.GET("/embed/:token", h.getEmbed)
+
 		// define group route protected
 		protected := api.Group("")
-		protected.Use(middlewares.AuthMiddleware(h.log))
+		protected.Use(middlewares.AuthMiddleware(h.log, h.services.User))
 		{
 			// define user group route /api/users
-			users := protected.Group("/users")
+			users := protected.Group("/users", middlewares.RequireSession())
 			{
+				users.GET("/me", h.getCurrentUser)
+				users.POST("/me/2fa/enroll", h.enrollTwoFactor)
+				users.POST("/me/2fa/confirm", h.confirmTwoFactor)
+				users.DELETE("/me/2fa", h.disableTwoFactor)
+				users.GET("/me/api-keys", h.getAPIKeys)
+				users.POST("/me/api-keys", h.createAPIKey)
+				users.DELETE("/me/api-keys/:id", h.revokeAPIKey)
 				users.GET("/:id", h.getUserByID)
-				users.POST("", h.createUser)
-				users.PATCH("/:id", h.updateUser)
+
+				admin := users.Group("", middlewares.RequireRole(models.RoleAdmin))
+				{
+					admin.POST("", h.createUser)
+					admin.GET("", h.getAllUsers)
+					admin.PATCH("/:id", h.updateUser)
+					admin.POST("/:id/deactivate", h.deactivateUser)
+					admin.POST("/:id/reactivate", h.reactivateUser)
+					admin.POST("/:id/unlock", h.unlockUser)
+					admin.DELETE("/:id/2fa", h.resetTwoFactor)
+					admin.DELETE("/:id", h.deleteUser)
+				}
+			}
+			// define organization group route /api/orgs
+			orgs := protected.Group("/orgs", middlewares.RequireSession())
+			{
+				orgs.GET("", h.getOrganizations)
+				orgs.POST("", middlewares.RequireRole(models.RoleAdmin), h.createOrganization)
+				orgs.POST("/:id/switch", h.switchOrganization)
+				orgs.PATCH("/current", middlewares.RequireOrgRole(models.RoleAdmin), h.updateOrganization)
+
+				members := orgs.Group("/current/members", middlewares.RequireOrgRole(models.RoleAdmin))
+				{
+					members.GET("", h.getOrganizationMembers)
+					members.POST("", h.addOrganizationMember)
+					members.PATCH("/:userId", h.updateOrganizationMember)
+					members.DELETE("/:userId", h.removeOrganizationMember)
+				}
+
+				invitations := orgs.Group("/current/invitations", middlewares.RequireOrgRole(models.RoleAdmin))
+				{
+					invitations.GET("", h.getInvitations)
+					invitations.POST("", h.createInvitation)
+					invitations.DELETE("/:id", h.revokeInvitation)
+				}
 			}
+
+			// define audit log routes /api/audit; access is scoped by the service
+			audit := protected.Group("/audit", middlewares.RequireSession())
+			{
+				audit.GET("", h.getAuditLog)
+				audit.GET("/export", h.exportAuditLog)
+			}
+
+			orgMember := middlewares.RequireOrgRole(models.RoleAdmin, models.RoleEditor, models.RoleViewer)
+			orgEditor := middlewares.RequireOrgRole(models.RoleAdmin, models.RoleEditor)
+			canRead := middlewares.RequireScope(auth.ScopeRead)
+			canWrite := middlewares.RequireScope(auth.ScopeWrite)
+
 			// define user group route /api/templates
-			templates := protected.Group("/templates")
+			templates := protected.Group("/templates", orgMember)
 			{
-				templates.POST("", h.createTemplate)
-				templates.GET("", h.getAllTemplates)
-				templates.GET("/:id", h.getTemplateByID)
-				templates.PATCH("/:id", h.updateTemplate)
+				templates.POST("", orgEditor, canWrite, h.createTemplate)
+				templates.GET("", canRead, h.getAllTemplates)
+				templates.GET("/:id", canRead, h.getTemplateByID)
+				templates.PATCH("/:id", orgEditor, canWrite, h.updateTemplate)
 			}
 
 			// TODO: переделать в dashboards
 			// define user group route /api/visualizations
-			visualizations := protected.Group("/visualizations")
+			visualizations := protected.Group("/visualizations", orgMember)
 			{
-				visualizations.POST("", h.createVisualization)
-				visualizations.GET("", h.getAllVisualizations)
+				visualizations.POST("", orgEditor, canWrite, h.createVisualization)
+				visualizations.GET("", canRead, h.getAllVisualizations)
 				// переделать в api/templates/{id}/dashboards
-				visualizations.GET("/t/:id", h.getVisualizationsByTemplateID)
-				visualizations.GET("/:id", h.getVisualizationByID)
-				visualizations.PATCH("/:id", h.updateVisualization)
-				visualizations.DELETE("/:id", h.deleteVisualization)
+				visualizations.GET("/t/:id", canRead, h.getVisualizationsByTemplateID)
+				visualizations.GET("/:id", canRead, h.getVisualizationByID)
+				visualizations.PATCH("/:id", orgEditor, canWrite, h.updateVisualization)
+				visualizations.DELETE("/:id", orgEditor, canWrite, h.deleteVisualization)
+				visualizations.POST("/:id/submit", orgEditor, canWrite, h.submitVisualization)
+				visualizations.POST("/:id/approve", orgEditor, canWrite, h.approveVisualization)
+				visualizations.POST("/:id/reject", orgEditor, canWrite, h.rejectVisualization)
+				visualizations.POST("/:id/publish", orgEditor, canWrite, h.publishVisualization)
+				visualizations.POST("/:id/archive", orgEditor, canWrite, h.archiveVisualization)
+				visualizations.POST("/:id/restore", orgEditor, canWrite, h.restoreVisualization)
+				visualizations.GET("/:id/reviews", canRead, h.getVisualizationReviews)
+				visualizations.GET("/:id/analytics", canRead, h.getVisualizationAnalytics)
+				visualizations.PUT("/:id/schedule", orgEditor, canWrite, h.scheduleVisualization)
+				visualizations.POST("/:id/embed-tokens", orgEditor, canWrite, h.createEmbedToken)
+				visualizations.GET("/:id/share-links", canRead, h.getShareLinks)
+				visualizations.POST("/:id/share-links", orgEditor, canWrite, h.createShareLink)
+				visualizations.POST("/:id/share-links/:linkId/rotate", orgEditor, canWrite, h.rotateShareLink)
+				visualizations.DELETE("/:id/share-links/:linkId", orgEditor, canWrite, h.revokeShareLink)
 			}
 		}
 	}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+	"visualizer-go/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// liveness only tells that the process serves requests; it must not depend
+// on the database, or an outage would get every instance restarted.
+func (h *Handler) liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthReport{Status: models.HealthStatusOK})
+}
+
+// readiness tells whether the instance should receive traffic.
+func (h *Handler) readiness(c *gin.Context) {
+	report := h.services.Health.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != models.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidInvitationID          = errors.New("invalid invitation ID format")
+	ErrInvitationInvalidRequestData = errors.New("invalid invitation request data")
+	ErrFailedToCreateInvitation     = errors.New("failed to create invitation")
+	ErrFailedToFetchInvitations     = errors.New("failed to fetch invitations")
+	ErrFailedToRevokeInvitation     = errors.New("failed to revoke invitation")
+	ErrFailedToAcceptInvitation     = errors.New("failed to accept invitation")
+)
+
+func (h *Handler) createInvitation(c *gin.Context) {
+	const op = "handler.Handler.createInvitation"
+
+	var invitationCreateDto dto.InvitationCreateDto
+	if err := c.ShouldBindJSON(&invitationCreateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvitationInvalidRequestData.Error(), err)
+		return
+	}
+
+	invitationID, err := h.services.Invitation.Create(c.Request.Context(), invitationCreateDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, service.ErrFailedToSendEmail) {
+			response.Error(c, http.StatusBadGateway, service.ErrFailedToSendEmail.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, ErrFailedToCreateInvitation.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Invitation sent successfully", invitationID)
+}
+
+func (h *Handler) getInvitations(c *gin.Context) {
+	const op = "handler.Handler.getInvitations"
+
+	invitations, err := h.services.Invitation.GetAll(c.Request.Context())
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusInternalServerError, ErrFailedToFetchInvitations.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitations fetched successfully", invitations)
+}
+
+func (h *Handler) revokeInvitation(c *gin.Context) {
+	const op = "handler.Handler.revokeInvitation"
+
+	invitationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidInvitationID.Error(), nil)
+		return
+	}
+
+	if err = h.services.Invitation.Revoke(c.Request.Context(), invitationID); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			response.Error(c, http.StatusNotFound, repository.ErrInvitationNotFound.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, ErrFailedToRevokeInvitation.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Invitation revoked successfully", nil)
+}
+
+func (h *Handler) acceptInvitation(c *gin.Context) {
+	const op = "handler.Handler.acceptInvitation"
+
+	var invitationAcceptDto dto.InvitationAcceptDto
+	if err := c.ShouldBindJSON(&invitationAcceptDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvitationInvalidRequestData.Error(), err)
+		return
+	}
+
+	acceptance, err := h.services.Invitation.Accept(c.Request.Context(), invitationAcceptDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, repository.ErrInvitationInvalid):
+			response.Error(c, http.StatusGone, repository.ErrInvitationInvalid.Error(), nil)
+		case errors.Is(err, repository.ErrUsernameOrEmailAlreadyUsed):
+			response.Error(c, http.StatusConflict, repository.ErrUsernameOrEmailAlreadyUsed.Error(), nil)
+		case errors.Is(err, repository.ErrInvitationAccountRequired):
+			response.Error(c, http.StatusBadRequest, repository.ErrInvitationAccountRequired.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, ErrFailedToAcceptInvitation.Error(), nil)
+		}
+		return
+	}
+
+	if acceptance.ExistingAccount {
+		response.Success(c, http.StatusOK, "Invitation accepted, sign in to continue", gin.H{
+			"existingAccount": true,
+		})
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Invitation accepted successfully", gin.H{
+		"token": acceptance.Token,
+	})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/api/auth/oidc"
+)
+
+var ErrFailedToStartSSO = errors.New("failed to start single sign-on")
+
+// oidcLogin redirects the browser to the identity provider.
+func (h *Handler) oidcLogin(c *gin.Context) {
+	const op = "handler.Handler.oidcLogin"
+
+	authURL, state, err := h.services.OIDC.Begin(c.Request.Context())
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, service.ErrSSODisabled) {
+			response.Error(c, http.StatusNotFound, service.ErrSSODisabled.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusBadGateway, ErrFailedToStartSSO.Error(), nil)
+		return
+	}
+
+	// Lax lets the cookie through on the top-level redirect back from the provider.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(service.OIDCStateTTL.Seconds()), oidcCookiePath, "", isSecureRequest(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// oidcCallback completes the sign-in and hands the session token to the
+// frontend in the URL fragment, which is never sent to servers or logged.
+func (h *Handler) oidcCallback(c *gin.Context) {
+	const op = "handler.Handler.oidcCallback"
+
+	state, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", isSecureRequest(c), true)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		h.requestLog(c).Error("identity provider returned an error", logger.Op(op),
+			slog.String("error", providerErr), slog.String("description", c.Query("error_description")))
+		h.redirectSSOError(c, "sso_failed")
+		return
+	}
+
+	token, err := h.services.OIDC.Complete(c.Request.Context(), c.Query("code"), c.Query("state"), state)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, service.ErrSSODisabled):
+			response.Error(c, http.StatusNotFound, service.ErrSSODisabled.Error(), nil)
+		case errors.Is(err, service.ErrUserDeactivated):
+			h.redirectSSOError(c, "user_deactivated")
+		case errors.Is(err, service.ErrSSOStateInvalid):
+			h.redirectSSOError(c, "sso_expired")
+		default:
+			h.redirectSSOError(c, "sso_failed")
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, h.origin+"/auth/callback#token="+url.QueryEscape(token))
+}
+
+func (h *Handler) redirectSSOError(c *gin.Context, code string) {
+	c.Redirect(http.StatusFound, h.origin+"/login?error="+url.QueryEscape(code))
+}
+
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidOrganizationID          = errors.New("invalid organization ID format")
+	ErrOrganizationInvalidRequestData = errors.New("invalid organization request data")
+	ErrFailedToFetchOrganizations     = errors.New("failed to fetch organizations")
+	ErrFailedToCreateOrganization     = errors.New("failed to create organization")
+	ErrFailedToSwitchOrganization     = errors.New("failed to switch organization")
+	ErrFailedToUpdateOrganization     = errors.New("failed to update organization")
+	ErrFailedToFetchMembers           = errors.New("failed to fetch organization members")
+	ErrFailedToUpdateMember           = errors.New("failed to update organization member")
+)
+
+func (h *Handler) getOrganizations(c *gin.Context) {
+	const op = "handler.Handler.getOrganizations"
+
+	identity, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrUnauthenticated))
+		response.Error(c, http.StatusUnauthorized, ErrUnauthenticated.Error(), nil)
+		return
+	}
+
+	organizations, err := h.services.Organization.GetAllForUser(c.Request.Context(), identity.UserID)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusInternalServerError, ErrFailedToFetchOrganizations.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Organizations fetched successfully", gin.H{
+		"organizations": organizations,
+		"activeOrgId":   identity.OrgID,
+	})
+}
+
+func (h *Handler) createOrganization(c *gin.Context) {
+	const op = "handler.Handler.createOrganization"
+
+	var organizationCreateDto dto.OrganizationCreateDto
+	if err := c.ShouldBindJSON(&organizationCreateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrOrganizationInvalidRequestData.Error(), err)
+		return
+	}
+
+	orgID, err := h.services.Organization.Create(c.Request.Context(), organizationCreateDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrOrganizationSlugTaken) {
+			response.Error(c, http.StatusConflict, repository.ErrOrganizationSlugTaken.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, ErrFailedToCreateOrganization.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Organization created successfully", orgID)
+}
+
+func (h *Handler) switchOrganization(c *gin.Context) {
+	const op = "handler.Handler.switchOrganization"
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidOrganizationID.Error(), nil)
+		return
+	}
+
+	token, err := h.services.Organization.Switch(c.Request.Context(), orgID)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			// Do not reveal whether an organization the user is not part of exists.
+			response.Error(c, http.StatusNotFound, repository.ErrOrganizationNotFound.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, ErrFailedToSwitchOrganization.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Organization switched successfully", gin.H{
+		"token":       token,
+		"activeOrgId": orgID,
+	})
+}
+
+func (h *Handler) updateOrganization(c *gin.Context) {
+	const op = "handler.Handler.updateOrganization"
+
+	var organizationUpdateDto dto.OrganizationUpdateDto
+	if err := c.ShouldBindJSON(&organizationUpdateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrOrganizationInvalidRequestData.Error(), err)
+		return
+	}
+
+	if err := h.services.Organization.Update(c.Request.Context(), organizationUpdateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, service.ErrTwoFactorSessionRequired):
+			response.Error(c, http.StatusForbidden, service.ErrTwoFactorSessionRequired.Error(), nil)
+		case errors.Is(err, repository.ErrOrganizationNotFound):
+			response.Error(c, http.StatusNotFound, repository.ErrOrganizationNotFound.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, ErrFailedToUpdateOrganization.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Organization updated successfully", nil)
+}
+
+func (h *Handler) getOrganizationMembers(c *gin.Context) {
+	const op = "handler.Handler.getOrganizationMembers"
+
+	members, err := h.services.Organization.GetMembers(c.Request.Context())
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusInternalServerError, ErrFailedToFetchMembers.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Organization members fetched successfully", members)
+}
+
+func (h *Handler) addOrganizationMember(c *gin.Context) {
+	const op = "handler.Handler.addOrganizationMember"
+
+	var memberCreateDto dto.OrganizationMemberCreateDto
+	if err := c.ShouldBindJSON(&memberCreateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrOrganizationInvalidRequestData.Error(), err)
+		return
+	}
+
+	if err := h.services.Organization.AddMember(c.Request.Context(), memberCreateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.organizationMemberError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Organization member added successfully", nil)
+}
+
+func (h *Handler) updateOrganizationMember(c *gin.Context) {
+	const op = "handler.Handler.updateOrganizationMember"
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidUserIDFormat.Error(), nil)
+		return
+	}
+
+	var memberUpdateDto dto.OrganizationMemberUpdateDto
+	if err = c.ShouldBindJSON(&memberUpdateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrOrganizationInvalidRequestData.Error(), err)
+		return
+	}
+
+	if err = h.services.Organization.UpdateMember(c.Request.Context(), userID, memberUpdateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.organizationMemberError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Organization member updated successfully", nil)
+}
+
+func (h *Handler) removeOrganizationMember(c *gin.Context) {
+	const op = "handler.Handler.removeOrganizationMember"
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidUserIDFormat.Error(), nil)
+		return
+	}
+
+	if err = h.services.Organization.RemoveMember(c.Request.Context(), userID); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.organizationMemberError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Organization member removed successfully", nil)
+}
+
+func (h *Handler) organizationMemberError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrMemberNotFound):
+		response.Error(c, http.StatusNotFound, repository.ErrMemberNotFound.Error(), nil)
+	case errors.Is(err, repository.ErrUserNotFound):
+		response.Error(c, http.StatusNotFound, ErrUserNotFound.Error(), nil)
+	case errors.Is(err, repository.ErrMemberAlreadyExists):
+		response.Error(c, http.StatusConflict, repository.ErrMemberAlreadyExists.Error(), nil)
+	case errors.Is(err, service.ErrLastOrganizationAdmin):
+		response.Error(c, http.StatusConflict, service.ErrLastOrganizationAdmin.Error(), nil)
+	default:
+		response.Error(c, http.StatusInternalServerError, ErrFailedToUpdateMember.Error(), nil)
+	}
+}
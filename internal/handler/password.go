@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrPasswordInvalidRequestData = errors.New("invalid password request data")
+	ErrFailedToRequestReset       = errors.New("failed to request password reset")
+	ErrFailedToResetPassword      = errors.New("failed to reset password")
+)
+
+func (h *Handler) forgotPassword(c *gin.Context) {
+	const op = "handler.Handler.forgotPassword"
+
+	var passwordForgotDto dto.PasswordForgotDto
+	if err := c.ShouldBindJSON(&passwordForgotDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrPasswordInvalidRequestData.Error(), err)
+		return
+	}
+
+	if err := h.services.PasswordReset.Forgot(c.Request.Context(), passwordForgotDto, c.ClientIP()); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		var rateLimitErr *service.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			tooManyRequests(c, rateLimitErr)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, ErrFailedToRequestReset.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, "If the account exists, a password reset link has been sent", nil)
+}
+
+func (h *Handler) resetPassword(c *gin.Context) {
+	const op = "handler.Handler.resetPassword"
+
+	var passwordResetDto dto.PasswordResetDto
+	if err := c.ShouldBindJSON(&passwordResetDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrPasswordInvalidRequestData.Error(), err)
+		return
+	}
+
+	if err := h.services.PasswordReset.Reset(c.Request.Context(), passwordResetDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrResetTokenInvalid) {
+			response.Error(c, http.StatusBadRequest, repository.ErrResetTokenInvalid.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, ErrFailedToResetPassword.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Password reset successfully", nil)
+}
+
+// tooManyRequests answers with 429 and a Retry-After header in whole seconds.
+func tooManyRequests(c *gin.Context, err *service.RateLimitError) {
+	seconds := int(math.Ceil(err.RetryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	response.Error(c, http.StatusTooManyRequests, err.Error(), nil)
+}
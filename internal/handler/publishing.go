@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var ErrReviewInvalidRequestData = errors.New("invalid review request data")
+
+func (h *Handler) submitVisualization(c *gin.Context) {
+	const op = "handler.Handler.submitVisualization"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	var visualizationSubmitDto dto.VisualizationSubmitDto
+	if err := c.ShouldBindJSON(&visualizationSubmitDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrReviewInvalidRequestData.Error(), err)
+		return
+	}
+
+	if err := h.services.Visualization.Submit(c.Request.Context(), visualizationID, visualizationSubmitDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.workflowError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Visualization submitted for review", nil)
+}
+
+func (h *Handler) approveVisualization(c *gin.Context) {
+	const op = "handler.Handler.approveVisualization"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	// The comment is optional, so an empty body is accepted.
+	var visualizationReviewDto dto.VisualizationReviewDto
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&visualizationReviewDto); err != nil {
+			h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+			response.Error(c, http.StatusBadRequest, ErrReviewInvalidRequestData.Error(), err)
+			return
+		}
+	}
+
+	status, err := h.services.Visualization.Approve(c.Request.Context(), visualizationID, visualizationReviewDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.workflowError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Visualization approved", gin.H{"status": status})
+}
+
+func (h *Handler) rejectVisualization(c *gin.Context) {
+	const op = "handler.Handler.rejectVisualization"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	var visualizationRejectDto dto.VisualizationRejectDto
+	if err := c.ShouldBindJSON(&visualizationRejectDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrReviewInvalidRequestData.Error(), err)
+		return
+	}
+
+	status, err := h.services.Visualization.Reject(c.Request.Context(), visualizationID, visualizationRejectDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.workflowError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Visualization rejected", gin.H{"status": status})
+}
+
+func (h *Handler) getVisualizationReviews(c *gin.Context) {
+	const op = "handler.Handler.getVisualizationReviews"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	reviews, err := h.services.Visualization.GetReviews(c.Request.Context(), visualizationID)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusInternalServerError, repository.ErrFailedToFetchReviews.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Reviews fetched successfully", reviews)
+}
+
+func (h *Handler) publishVisualization(c *gin.Context) {
+	const op = "handler.Handler.publishVisualization"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	if err := h.services.Visualization.Publish(c.Request.Context(), visualizationID); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.workflowError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Visualization published", nil)
+}
+
+func (h *Handler) archiveVisualization(c *gin.Context) {
+	const op = "handler.Handler.archiveVisualization"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	if err := h.services.Visualization.Archive(c.Request.Context(), visualizationID); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.workflowError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Visualization archived", nil)
+}
+
+func (h *Handler) restoreVisualization(c *gin.Context) {
+	const op = "handler.Handler.restoreVisualization"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	if err := h.services.Visualization.Restore(c.Request.Context(), visualizationID); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.workflowError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Visualization restored", nil)
+}
+
+func (h *Handler) scheduleVisualization(c *gin.Context) {
+	const op = "handler.Handler.scheduleVisualization"
+
+	visualizationID, ok := h.visualizationParam(c, op)
+	if !ok {
+		return
+	}
+
+	var visualizationScheduleDto dto.VisualizationScheduleDto
+	if err := c.ShouldBindJSON(&visualizationScheduleDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrVisualizationInvalidRequestData.Error(), err)
+		return
+	}
+
+	if err := h.services.Visualization.Schedule(c.Request.Context(), visualizationID, visualizationScheduleDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.workflowError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Visualization schedule updated", visualizationScheduleDto)
+}
+
+func (h *Handler) visualizationParam(c *gin.Context, op string) (uuid.UUID, bool) {
+	visualizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), nil)
+		return uuid.Nil, false
+	}
+	return visualizationID, true
+}
+
+// workflowError maps the errors of a publishing workflow transition.
+func (h *Handler) workflowError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrVisualizationNotFound):
+		response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+	case errors.Is(err, repository.ErrInvalidTransition):
+		response.Error(c, http.StatusConflict, repository.ErrInvalidTransition.Error(), nil)
+	case errors.Is(err, repository.ErrInvalidReviewers):
+		response.Error(c, http.StatusBadRequest, repository.ErrInvalidReviewers.Error(), nil)
+	case errors.Is(err, service.ErrSelfReview):
+		response.Error(c, http.StatusBadRequest, service.ErrSelfReview.Error(), nil)
+	case errors.Is(err, service.ErrScheduleInPast):
+		response.Error(c, http.StatusBadRequest, service.ErrScheduleInPast.Error(), nil)
+	case errors.Is(err, service.ErrScheduleOrder):
+		response.Error(c, http.StatusBadRequest, service.ErrScheduleOrder.Error(), nil)
+	case errors.Is(err, repository.ErrNotPendingReviewer):
+		response.Error(c, http.StatusForbidden, repository.ErrNotPendingReviewer.Error(), nil)
+	case errors.Is(err, auth.ErrInsufficientScope):
+		response.Error(c, http.StatusForbidden, auth.ErrInsufficientScope.Error(), nil)
+	default:
+		response.Error(c, http.StatusInternalServerError, ErrFailedToUpdateVisualization.Error(), nil)
+	}
+}
@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const shareViewerCookie = "share_viewer"
+
+var (
+	ErrShareLinkInvalidRequestData = errors.New("invalid share link request data")
+	ErrInvalidShareLinkID          = errors.New("invalid share link ID format")
+	ErrInvalidShareID              = errors.New("invalid share ID format")
+)
+
+// getVisualizationByShareID serves a published visualization through a share
+// link. Protected links answer 401 with passwordRequired until the viewer
+// cookie set by unlockShareLink is present. Clients that send the ETag of
+// the current version in If-None-Match get 304 without a body; the view is
+// counted either way.
+func (h *Handler) getVisualizationByShareID(c *gin.Context) {
+	const op = "handler.Handler.getVisualizationByShareID"
+
+	// Errors must not be stored, or a revoked link would keep answering
+	// from a CDN.
+	c.Header("Cache-Control", "no-store")
+
+	shareID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidShareID.Error(), nil)
+		return
+	}
+
+	viewer, _ := c.Cookie(shareViewerCookie)
+
+	shared, err := h.services.ShareLink.Open(c.Request.Context(), shareID, viewer, viewerFromRequest(c))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, service.ErrSharePasswordRequired):
+			response.Error(c, http.StatusUnauthorized, service.ErrSharePasswordRequired.Error(), gin.H{"passwordRequired": true})
+		case errors.Is(err, service.ErrShareLinkExpired):
+			response.Error(c, http.StatusGone, service.ErrShareLinkExpired.Error(), nil)
+		case errors.Is(err, repository.ErrShareLinkNotFound), errors.Is(err, repository.ErrVisualizationNotFound):
+			response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, ErrFailedToFetchVisualizations.Error(), nil)
+		}
+		return
+	}
+
+	h.setShareCacheHeaders(c, shared)
+
+	if etagMatches(c.GetHeader("If-None-Match"), shared.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// The timestamp is the publish time, so the body only changes together
+	// with the ETag.
+	response.SuccessAt(c, http.StatusOK, "Visualization fetched successfully", shared.Visualization, shared.Visualization.UpdatedAt)
+}
+
+// setShareCacheHeaders lets browsers, and for public links a CDN, reuse the
+// response for the configured time, never beyond the expiry of the link.
+// Views served from a cache without revalidation are not counted.
+func (h *Handler) setShareCacheHeaders(c *gin.Context, shared service.SharedVisualization) {
+	c.Header("ETag", shared.ETag)
+
+	if shared.Protected {
+		// The viewer cookie decides between 200 and 401 for the same URL.
+		c.Header("Cache-Control", "private, no-cache")
+		c.Header("Vary", "Cookie")
+		return
+	}
+
+	maxAge, sharedMaxAge := h.shareCache.MaxAge, h.shareCache.SharedMaxAge
+	if shared.ExpiresAt != nil {
+		left := time.Until(*shared.ExpiresAt)
+		maxAge, sharedMaxAge = min(maxAge, left), min(sharedMaxAge, left)
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, s-maxage=%d",
+		int(maxAge.Seconds()), int(sharedMaxAge.Seconds())))
+}
+
+// etagMatches implements the weak comparison If-None-Match asks for: W/
+// prefixes are ignored and * matches any current version.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// unlockShareLink checks the password of a protected share link and sets a
+// short-lived viewer cookie limited to that link's path.
+func (h *Handler) unlockShareLink(c *gin.Context) {
+	const op = "handler.Handler.unlockShareLink"
+
+	shareID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidShareID.Error(), nil)
+		return
+	}
+
+	var shareLinkUnlockDto dto.ShareLinkUnlockDto
+	if err = c.ShouldBindJSON(&shareLinkUnlockDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrShareLinkInvalidRequestData.Error(), err)
+		return
+	}
+
+	viewer, err := h.services.ShareLink.Unlock(c.Request.Context(), shareID, shareLinkUnlockDto, c.ClientIP())
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		var rateLimitErr *service.RateLimitError
+		switch {
+		case errors.As(err, &rateLimitErr):
+			tooManyRequests(c, rateLimitErr)
+		case errors.Is(err, service.ErrSharePasswordIncorrect):
+			response.Error(c, http.StatusUnauthorized, service.ErrSharePasswordIncorrect.Error(), nil)
+		case errors.Is(err, service.ErrShareLinkNotProtected):
+			response.Error(c, http.StatusBadRequest, service.ErrShareLinkNotProtected.Error(), nil)
+		case errors.Is(err, service.ErrShareLinkExpired):
+			response.Error(c, http.StatusGone, service.ErrShareLinkExpired.Error(), nil)
+		case errors.Is(err, repository.ErrShareLinkNotFound):
+			response.Error(c, http.StatusNotFound, repository.ErrShareLinkNotFound.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, ErrFailedToFetchVisualizations.Error(), nil)
+		}
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(shareViewerCookie, viewer, int(service.ShareViewerTTL.Seconds()),
+		"/api/visualizations/share/"+shareID.String(), "", isSecureRequest(c), true)
+
+	response.Success(c, http.StatusOK, "Share link unlocked", nil)
+}
+
+func (h *Handler) getShareLinks(c *gin.Context) {
+	const op = "handler.Handler.getShareLinks"
+
+	visualizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), nil)
+		return
+	}
+
+	links, err := h.services.ShareLink.GetAll(c.Request.Context(), visualizationID)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusInternalServerError, repository.ErrFailedToFetchShareLinks.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Share links fetched successfully", links)
+}
+
+func (h *Handler) createShareLink(c *gin.Context) {
+	const op = "handler.Handler.createShareLink"
+
+	visualizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), nil)
+		return
+	}
+
+	var shareLinkCreateDto dto.ShareLinkCreateDto
+	if err = c.ShouldBindJSON(&shareLinkCreateDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrShareLinkInvalidRequestData.Error(), err)
+		return
+	}
+
+	link, err := h.services.ShareLink.Create(c.Request.Context(), visualizationID, shareLinkCreateDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, service.ErrShareLinkExpiryInPast):
+			response.Error(c, http.StatusBadRequest, service.ErrShareLinkExpiryInPast.Error(), nil)
+		case errors.Is(err, auth.ErrInsufficientScope):
+			response.Error(c, http.StatusForbidden, auth.ErrInsufficientScope.Error(), nil)
+		case errors.Is(err, repository.ErrVisualizationNotFound):
+			response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, repository.ErrFailedToCreateShareLink.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, "Share link created successfully", link)
+}
+
+func (h *Handler) rotateShareLink(c *gin.Context) {
+	const op = "handler.Handler.rotateShareLink"
+
+	visualizationID, linkID, ok := h.shareLinkParams(c, op)
+	if !ok {
+		return
+	}
+
+	shareID, err := h.services.ShareLink.Rotate(c.Request.Context(), visualizationID, linkID)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, auth.ErrInsufficientScope):
+			response.Error(c, http.StatusForbidden, auth.ErrInsufficientScope.Error(), nil)
+		case errors.Is(err, repository.ErrShareLinkNotFound):
+			response.Error(c, http.StatusNotFound, repository.ErrShareLinkNotFound.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, repository.ErrFailedToUpdateShareLink.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Share link rotated successfully", gin.H{"shareId": shareID})
+}
+
+func (h *Handler) revokeShareLink(c *gin.Context) {
+	const op = "handler.Handler.revokeShareLink"
+
+	visualizationID, linkID, ok := h.shareLinkParams(c, op)
+	if !ok {
+		return
+	}
+
+	if err := h.services.ShareLink.Revoke(c.Request.Context(), visualizationID, linkID); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrShareLinkNotFound) {
+			response.Error(c, http.StatusNotFound, repository.ErrShareLinkNotFound.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, repository.ErrFailedToUpdateShareLink.Error(), nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Share link revoked successfully", nil)
+}
+
+func (h *Handler) shareLinkParams(c *gin.Context, op string) (uuid.UUID, uuid.UUID, bool) {
+	visualizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	linkID, err := uuid.Parse(c.Param("linkId"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidShareLinkID.Error(), nil)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return visualizationID, linkID, true
+}
@@ -0,0 +1,28 @@
+package handler
+
+import "testing"
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"lx2k9c-0a1b2c"`
+
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{`"lx2k9c-0a1b2c"`, true},
+		{`W/"lx2k9c-0a1b2c"`, true},
+		{`"other", "lx2k9c-0a1b2c"`, true},
+		{`"other",W/"lx2k9c-0a1b2c"`, true},
+		{`*`, true},
+		{`"other"`, false},
+		{`lx2k9c-0a1b2c`, false},
+		{`"lx2k9c-0a1b2"`, false},
+		{``, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifNoneMatch, etag); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.ifNoneMatch, got, tt.want)
+		}
+	}
+}
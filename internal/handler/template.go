@@ -2,11 +2,12 @@ package handler
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"strconv"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
 	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -51,7 +52,7 @@ func (h *Handler) getAllTemplates(c *gin.Context) {
 
 	templates, err := h.services.Template.GetAll(c.Request.Context(), includeCanvases)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusInternalServerError, ErrFailedToFetchTemplates.Error(), err)
 		return
 	}
@@ -76,21 +77,21 @@ func (h *Handler) getTemplateByID(c *gin.Context) {
 
 	templateIDStr := c.Param("id")
 	if templateIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrTemplateIDMissing))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrTemplateIDMissing))
 		response.Error(c, http.StatusBadRequest, ErrTemplateIDMissing.Error(), nil)
 		return
 	}
 
 	templateID, err := uuid.Parse(templateIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrInvalidTemplateID.Error(), err)
 		return
 	}
 
 	template, err := h.services.Template.GetByID(c.Request.Context(), templateID)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusNotFound, ErrTemplateNotFound.Error(), err)
 		return
 	}
@@ -103,14 +104,14 @@ func (h *Handler) createTemplate(c *gin.Context) {
 
 	var templateCreateDto dto.TemplateCreateDto
 	if err := c.ShouldBindJSON(&templateCreateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrTemplateInvalidRequestData.Error(), err)
 		return
 	}
 
 	templateID, err := h.services.Template.Create(c.Request.Context(), templateCreateDto)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusInternalServerError, ErrFailedToCreateTemplate.Error(), err)
 		return
 	}
@@ -123,27 +124,31 @@ func (h *Handler) updateTemplate(c *gin.Context) {
 
 	templateIDStr := c.Param("id")
 	if templateIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrTemplateIDMissing))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrTemplateIDMissing))
 		response.Error(c, http.StatusBadRequest, ErrTemplateIDMissing.Error(), nil)
 		return
 	}
 
 	templateID, err := uuid.Parse(templateIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrInvalidTemplateID.Error(), err)
 		return
 	}
 
 	var templateUpdateDto dto.TemplateUpdateDto
 	if err = c.ShouldBindJSON(&templateUpdateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrTemplateInvalidRequestData.Error(), err)
 		return
 	}
 
 	if err = h.services.Template.Update(c.Request.Context(), templateID, templateUpdateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrTemplateNotFound) {
+			response.Error(c, http.StatusNotFound, ErrTemplateNotFound.Error(), nil)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, ErrFailedToUpdateTemplate.Error(), err)
 		return
 	}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTwoFactorInvalidRequestData = errors.New("invalid two-factor request data")
+	ErrFailedToUpdateTwoFactor     = errors.New("failed to update two-factor settings")
+	ErrFailedToVerifyTwoFactor     = errors.New("failed to verify two-factor code")
+)
+
+func (h *Handler) verifyTwoFactor(c *gin.Context) {
+	const op = "handler.Handler.verifyTwoFactor"
+
+	var verifyDto dto.TwoFactorVerifyDto
+	if err := c.ShouldBindJSON(&verifyDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrTwoFactorInvalidRequestData.Error(), err)
+		return
+	}
+
+	user, token, err := h.services.VerifyTwoFactor(c.Request.Context(), verifyDto, c.ClientIP())
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		var rateLimitErr *service.RateLimitError
+		switch {
+		case errors.As(err, &rateLimitErr):
+			tooManyRequests(c, rateLimitErr)
+		case errors.Is(err, service.ErrInvalidChallenge):
+			response.Error(c, http.StatusUnauthorized, service.ErrInvalidChallenge.Error(), nil)
+		case errors.Is(err, repository.ErrTwoFactorCodeInvalid), errors.Is(err, service.ErrTwoFactorNotEnabled):
+			response.Error(c, http.StatusBadRequest, repository.ErrTwoFactorCodeInvalid.Error(), nil)
+		case errors.Is(err, service.ErrUserDeactivated):
+			response.Error(c, http.StatusForbidden, service.ErrUserDeactivated.Error(), nil)
+		default:
+			response.Error(c, http.StatusInternalServerError, ErrFailedToVerifyTwoFactor.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Logged in successfully", gin.H{
+		"user":  user,
+		"token": token,
+	})
+}
+
+func (h *Handler) enrollTwoFactor(c *gin.Context) {
+	const op = "handler.Handler.enrollTwoFactor"
+
+	enrollment, err := h.services.TwoFactor.Enroll(c.Request.Context())
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.twoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Two-factor enrollment started", enrollment)
+}
+
+func (h *Handler) confirmTwoFactor(c *gin.Context) {
+	const op = "handler.Handler.confirmTwoFactor"
+
+	var confirmDto dto.TwoFactorConfirmDto
+	if err := c.ShouldBindJSON(&confirmDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrTwoFactorInvalidRequestData.Error(), err)
+		return
+	}
+
+	recoveryCodes, err := h.services.TwoFactor.Confirm(c.Request.Context(), confirmDto)
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.twoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Two-factor authentication enabled", gin.H{
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+func (h *Handler) disableTwoFactor(c *gin.Context) {
+	const op = "handler.Handler.disableTwoFactor"
+
+	var codeDto dto.TwoFactorCodeDto
+	if err := c.ShouldBindJSON(&codeDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrTwoFactorInvalidRequestData.Error(), err)
+		return
+	}
+
+	if err := h.services.TwoFactor.Disable(c.Request.Context(), codeDto); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.twoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+func (h *Handler) resetTwoFactor(c *gin.Context) {
+	const op = "handler.Handler.resetTwoFactor"
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(c, http.StatusBadRequest, ErrInvalidUserIDFormat.Error(), nil)
+		return
+	}
+
+	if err = h.services.TwoFactor.Reset(c.Request.Context(), userID); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		h.twoFactorError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, "Two-factor authentication reset", nil)
+}
+
+func (h *Handler) twoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		response.Error(c, http.StatusNotFound, ErrUserNotFound.Error(), nil)
+	case errors.Is(err, repository.ErrTwoFactorAlreadyEnabled):
+		response.Error(c, http.StatusConflict, repository.ErrTwoFactorAlreadyEnabled.Error(), nil)
+	case errors.Is(err, repository.ErrTwoFactorNotPending):
+		response.Error(c, http.StatusConflict, repository.ErrTwoFactorNotPending.Error(), nil)
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		response.Error(c, http.StatusConflict, service.ErrTwoFactorNotEnabled.Error(), nil)
+	case errors.Is(err, repository.ErrTwoFactorCodeInvalid):
+		response.Error(c, http.StatusBadRequest, repository.ErrTwoFactorCodeInvalid.Error(), nil)
+	default:
+		response.Error(c, http.StatusInternalServerError, ErrFailedToUpdateTwoFactor.Error(), nil)
+	}
+}
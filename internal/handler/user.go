@@ -2,13 +2,16 @@ package handler
 
 import (
 	"errors"
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"math"
 	"net/http"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
 	"visualizer-go/internal/lib/response"
 	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
 )
 
 var (
@@ -18,6 +21,8 @@ var (
 	ErrFailedToUpdateUser     = errors.New("failed to update user")
 	ErrUserNotFound           = errors.New("user not found")
 	ErrUserInvalidRequestData = errors.New("invalid user request data")
+	ErrFailedToFetchUsers     = errors.New("failed to fetch users")
+	ErrFailedToDeleteUser     = errors.New("failed to delete user")
 )
 
 func (h *Handler) login(ctx *gin.Context) {
@@ -25,21 +30,38 @@ func (h *Handler) login(ctx *gin.Context) {
 
 	var userLoginDto dto.UserLoginDto
 	if err := ctx.ShouldBind(&userLoginDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(ctx, http.StatusBadRequest, ErrUserInvalidRequestData.Error(), err)
 		return
 	}
 
-	user, _, err := h.services.Login(ctx.Request.Context(), userLoginDto)
+	user, token, err := h.services.Login(ctx.Request.Context(), userLoginDto, ctx.ClientIP())
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
-		response.Error(ctx, http.StatusBadRequest, err.Error(), repository.ErrInvalidCredentials.Error())
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		var rateLimitErr *service.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			tooManyRequests(ctx, rateLimitErr)
+			return
+		}
+		var twoFactorErr *service.TwoFactorRequiredError
+		if errors.As(err, &twoFactorErr) {
+			response.Success(ctx, http.StatusOK, "Two-factor authentication required", gin.H{
+				"twoFactorRequired": true,
+				"challengeToken":    twoFactorErr.ChallengeToken,
+			})
+			return
+		}
+		if errors.Is(err, service.ErrUserDeactivated) {
+			response.Error(ctx, http.StatusForbidden, service.ErrUserDeactivated.Error(), nil)
+			return
+		}
+		response.Error(ctx, http.StatusBadRequest, repository.ErrInvalidCredentials.Error(), repository.ErrInvalidCredentials.Error())
 		return
 	}
 
 	response.Success(ctx, http.StatusOK, "Logged in successfully", gin.H{
 		"user":  user,
-		"token": "test_token_123",
+		"token": token,
 	})
 }
 
@@ -48,21 +70,25 @@ func (h *Handler) getUserByID(ctx *gin.Context) {
 
 	userIDStr := ctx.Param("id")
 	if userIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrUserIDMissing))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(ErrUserIDMissing))
 		response.Error(ctx, http.StatusBadRequest, ErrUserIDMissing.Error(), ErrUserIDMissing.Error())
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(ctx, http.StatusBadRequest, ErrInvalidUserIDFormat.Error(), ErrInvalidUserIDFormat.Error())
 		return
 	}
 
-	user, err := h.services.User.GetByID(ctx, userID)
+	user, err := h.services.User.GetByID(ctx.Request.Context(), userID)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrUserNotFound) {
+			response.Error(ctx, http.StatusNotFound, ErrUserNotFound.Error(), ErrUserNotFound.Error())
+			return
+		}
 		response.Error(ctx, http.StatusInternalServerError, ErrUserNotFound.Error(), ErrUserNotFound.Error())
 		return
 	}
@@ -75,13 +101,17 @@ func (h *Handler) createUser(ctx *gin.Context) {
 
 	var userCreateDto dto.UserCreateDto
 	if err := ctx.ShouldBindJSON(&userCreateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(ctx, http.StatusBadRequest, ErrUserInvalidRequestData.Error(), err)
 		return
 	}
 
 	if err := h.services.User.Create(ctx.Request.Context(), userCreateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrUsernameOrEmailAlreadyUsed) {
+			response.Error(ctx, http.StatusConflict, repository.ErrUsernameOrEmailAlreadyUsed.Error(), nil)
+			return
+		}
 		response.Error(ctx, http.StatusInternalServerError, ErrFailedToCreateUser.Error(), err)
 		return
 	}
@@ -94,30 +124,188 @@ func (h *Handler) updateUser(ctx *gin.Context) {
 
 	userIDStr := ctx.Param("id")
 	if userIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrUserIDMissing))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(ErrUserIDMissing))
 		response.Error(ctx, http.StatusBadRequest, ErrUserIDMissing.Error(), nil)
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(ctx, http.StatusBadRequest, ErrInvalidUserIDFormat.Error(), err)
 		return
 	}
 
 	var userUpdateDto dto.UserUpdateDto
 	if err = ctx.ShouldBindJSON(&userUpdateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(ctx, http.StatusBadRequest, ErrUserInvalidRequestData.Error(), err)
 		return
 	}
 
 	if err = h.services.User.Update(ctx.Request.Context(), userID, userUpdateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(ctx, http.StatusInternalServerError, ErrFailedToUpdateUser.Error(), err)
 		return
 	}
 
 	response.Success(ctx, http.StatusOK, "User updated successfully", nil)
 }
+
+func (h *Handler) getCurrentUser(ctx *gin.Context) {
+	const op = "handler.Handler.getCurrentUser"
+
+	identity, ok := auth.FromContext(ctx.Request.Context())
+	if !ok {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(ErrUnauthenticated))
+		response.Error(ctx, http.StatusUnauthorized, ErrUnauthenticated.Error(), nil)
+		return
+	}
+
+	user, err := h.services.User.GetByID(ctx.Request.Context(), identity.UserID)
+	if err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(ctx, http.StatusNotFound, ErrUserNotFound.Error(), nil)
+		return
+	}
+
+	response.Success(ctx, http.StatusOK, "User fetched successfully", user)
+}
+
+func (h *Handler) getAllUsers(ctx *gin.Context) {
+	const op = "handler.Handler.getAllUsers"
+
+	var query dto.UserListQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(ctx, http.StatusBadRequest, ErrUserInvalidRequestData.Error(), err)
+		return
+	}
+
+	offset := (query.Page - 1) * query.Limit
+
+	users, rowCount, err := h.services.User.GetAll(ctx.Request.Context(), query.Search, query.Limit, offset)
+	if err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(ctx, http.StatusInternalServerError, ErrFailedToFetchUsers.Error(), nil)
+		return
+	}
+
+	pageCount := int(math.Ceil(float64(rowCount) / float64(query.Limit)))
+
+	response.Success(ctx, http.StatusOK, "Users fetched successfully", gin.H{
+		"users": users,
+		"pagination": gin.H{
+			"rowCount":    rowCount,
+			"pageCount":   pageCount,
+			"currentPage": query.Page,
+			"limit":       query.Limit,
+			"hasMore":     query.Page < pageCount,
+		}})
+}
+
+func (h *Handler) deactivateUser(ctx *gin.Context) {
+	h.setUserActive(ctx, "handler.Handler.deactivateUser", false)
+}
+
+func (h *Handler) reactivateUser(ctx *gin.Context) {
+	h.setUserActive(ctx, "handler.Handler.reactivateUser", true)
+}
+
+func (h *Handler) setUserActive(ctx *gin.Context, op string, active bool) {
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(ctx, http.StatusBadRequest, ErrInvalidUserIDFormat.Error(), nil)
+		return
+	}
+
+	if active {
+		err = h.services.User.Reactivate(ctx.Request.Context(), userID)
+	} else {
+		err = h.services.User.Deactivate(ctx.Request.Context(), userID)
+	}
+	if err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, repository.ErrUserNotFound):
+			response.Error(ctx, http.StatusNotFound, ErrUserNotFound.Error(), nil)
+		case errors.Is(err, service.ErrCannotModifySelf):
+			response.Error(ctx, http.StatusConflict, service.ErrCannotModifySelf.Error(), nil)
+		default:
+			response.Error(ctx, http.StatusInternalServerError, ErrFailedToUpdateUser.Error(), nil)
+		}
+		return
+	}
+
+	if active {
+		response.Success(ctx, http.StatusOK, "User reactivated successfully", nil)
+		return
+	}
+	response.Success(ctx, http.StatusOK, "User deactivated successfully", nil)
+}
+
+func (h *Handler) unlockUser(ctx *gin.Context) {
+	const op = "handler.Handler.unlockUser"
+
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(ctx, http.StatusBadRequest, ErrInvalidUserIDFormat.Error(), nil)
+		return
+	}
+
+	if err = h.services.User.Unlock(ctx.Request.Context(), userID); err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrUserNotFound) {
+			response.Error(ctx, http.StatusNotFound, ErrUserNotFound.Error(), nil)
+			return
+		}
+		response.Error(ctx, http.StatusInternalServerError, ErrFailedToUpdateUser.Error(), nil)
+		return
+	}
+
+	response.Success(ctx, http.StatusOK, "User unlocked successfully", nil)
+}
+
+func (h *Handler) deleteUser(ctx *gin.Context) {
+	const op = "handler.Handler.deleteUser"
+
+	userID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(ctx, http.StatusBadRequest, ErrInvalidUserIDFormat.Error(), nil)
+		return
+	}
+
+	var query dto.UserDeleteQuery
+	if err = ctx.ShouldBindQuery(&query); err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		response.Error(ctx, http.StatusBadRequest, ErrUserInvalidRequestData.Error(), err)
+		return
+	}
+
+	reassignTo := uuid.Nil
+	if query.ReassignTo != "" {
+		reassignTo = uuid.MustParse(query.ReassignTo)
+	}
+
+	if err = h.services.User.Delete(ctx.Request.Context(), userID, reassignTo); err != nil {
+		h.requestLog(ctx).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, repository.ErrUserNotFound):
+			response.Error(ctx, http.StatusNotFound, ErrUserNotFound.Error(), nil)
+		case errors.Is(err, service.ErrCannotModifySelf):
+			response.Error(ctx, http.StatusConflict, service.ErrCannotModifySelf.Error(), nil)
+		case errors.Is(err, service.ErrInvalidReassignment):
+			response.Error(ctx, http.StatusBadRequest, service.ErrInvalidReassignment.Error(), nil)
+		case errors.Is(err, repository.ErrReassignTargetNotMember):
+			response.Error(ctx, http.StatusBadRequest, repository.ErrReassignTargetNotMember.Error(), nil)
+		default:
+			response.Error(ctx, http.StatusInternalServerError, ErrFailedToDeleteUser.Error(), nil)
+		}
+		return
+	}
+
+	response.Success(ctx, http.StatusOK, "User deleted successfully", nil)
+}
@@ -2,10 +2,13 @@ package handler
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
 	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/repository"
+	"visualizer-go/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -30,7 +33,7 @@ func (h *Handler) getAllVisualizations(c *gin.Context) {
 
 	templates, err := h.services.Visualization.GetAll(c.Request.Context())
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusInternalServerError, ErrFailedToFetchVisualizations.Error(), err)
 		return
 	}
@@ -43,21 +46,21 @@ func (h *Handler) getVisualizationsByTemplateID(c *gin.Context) {
 
 	templateIDStr := c.Param("id")
 	if templateIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrVisualizationIDMissing))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrVisualizationIDMissing))
 		response.Error(c, http.StatusBadRequest, ErrVisualizationIDMissing.Error(), nil)
 		return
 	}
 
 	templateID, err := uuid.Parse(templateIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), err)
 		return
 	}
 
 	templates, err := h.services.Visualization.GetByTemplateID(c.Request.Context(), templateID)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusInternalServerError, ErrFailedToFetchVisualizations.Error(), err)
 		return
 	}
@@ -70,48 +73,21 @@ func (h *Handler) getVisualizationByID(c *gin.Context) {
 
 	templateIDStr := c.Param("id")
 	if templateIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrVisualizationIDMissing))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrVisualizationIDMissing))
 		response.Error(c, http.StatusBadRequest, ErrVisualizationIDMissing.Error(), nil)
 		return
 	}
 
 	templateID, err := uuid.Parse(templateIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), err)
 		return
 	}
 
 	template, err := h.services.Visualization.GetByID(c.Request.Context(), templateID)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
-		response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), err)
-		return
-	}
-
-	response.Success(c, http.StatusOK, "Visualization fetched successfully", template)
-}
-
-func (h *Handler) getVisualizationByShareID(c *gin.Context) {
-	const op = "handler.Handler.getVisualizationByShareID"
-
-	templateIDStr := c.Param("id")
-	if templateIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrVisualizationIDMissing))
-		response.Error(c, http.StatusBadRequest, ErrVisualizationIDMissing.Error(), nil)
-		return
-	}
-
-	templateID, err := uuid.Parse(templateIDStr)
-	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
-		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), err)
-		return
-	}
-
-	template, err := h.services.Visualization.GetByShareID(c.Request.Context(), templateID)
-	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), err)
 		return
 	}
@@ -124,14 +100,26 @@ func (h *Handler) createVisualization(c *gin.Context) {
 
 	var visualizationCreateDto dto.VisualizationCreateDto
 	if err := c.ShouldBindJSON(&visualizationCreateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrVisualizationInvalidRequestData.Error(), err)
 		return
 	}
 
+	identity, ok := auth.FromContext(c.Request.Context())
+	if !ok {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrUnauthenticated))
+		response.Error(c, http.StatusUnauthorized, ErrUnauthenticated.Error(), nil)
+		return
+	}
+	visualizationCreateDto.UserID = identity.UserID
+
 	templateID, err := h.services.Visualization.Create(c.Request.Context(), visualizationCreateDto)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrTemplateNotFound) {
+			response.Error(c, http.StatusBadRequest, ErrTemplateNotFound.Error(), nil)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, ErrFailedToCreateVisualization.Error(), err)
 		return
 	}
@@ -144,27 +132,41 @@ func (h *Handler) updateVisualization(c *gin.Context) {
 
 	templateIDStr := c.Param("id")
 	if templateIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrVisualizationIDMissing))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrVisualizationIDMissing))
 		response.Error(c, http.StatusBadRequest, ErrVisualizationIDMissing.Error(), nil)
 		return
 	}
 
 	templateID, err := uuid.Parse(templateIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), err)
 		return
 	}
 
 	var visualizationUpdateDto dto.VisualizationUpdateDto
 	if err = c.ShouldBindJSON(&visualizationUpdateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrVisualizationInvalidRequestData.Error(), err)
 		return
 	}
 
 	if err = h.services.Visualization.Update(c.Request.Context(), templateID, visualizationUpdateDto); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		switch {
+		case errors.Is(err, repository.ErrVisualizationNotFound):
+			response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+			return
+		case errors.Is(err, repository.ErrTemplateNotFound):
+			response.Error(c, http.StatusBadRequest, ErrTemplateNotFound.Error(), nil)
+			return
+		case errors.Is(err, service.ErrVisualizationLocked):
+			response.Error(c, http.StatusConflict, service.ErrVisualizationLocked.Error(), nil)
+			return
+		case errors.Is(err, repository.ErrInvalidTransition):
+			response.Error(c, http.StatusConflict, repository.ErrInvalidTransition.Error(), nil)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, ErrFailedToUpdateVisualization.Error(), err)
 		return
 	}
@@ -172,30 +174,42 @@ func (h *Handler) updateVisualization(c *gin.Context) {
 	response.Success(c, http.StatusOK, "Visualization updated successfully", nil)
 }
 
+// metric records a view reported by a page rendering a published
+// visualization. Bots and repeated views are not counted, and reports are
+// throttled per client network.
 func (h *Handler) metric(c *gin.Context) {
 	const op = "handler.Handler.metric"
 
 	templateIDStr := c.Param("id")
 	if templateIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrVisualizationIDMissing))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrVisualizationIDMissing))
 		response.Error(c, http.StatusBadRequest, ErrVisualizationIDMissing.Error(), nil)
 		return
 	}
 
 	templateID, err := uuid.Parse(templateIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), err)
 		return
 	}
 
-	if err = h.services.IncrementViewCount(c.Request.Context(), templateID); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
-		response.Error(c, http.StatusInternalServerError, ErrFailedToIncrementViewCountVisualization.Error(), err)
+	if err = h.services.Analytics.TrackView(c.Request.Context(), templateID, viewerFromRequest(c)); err != nil {
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		var rateLimitErr *service.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			tooManyRequests(c, rateLimitErr)
+			return
+		}
+		if errors.Is(err, repository.ErrVisualizationNotFound) {
+			response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, ErrFailedToIncrementViewCountVisualization.Error(), nil)
 		return
 	}
 
-	response.Success(c, http.StatusOK, "view count incremented", nil)
+	response.Success(c, http.StatusOK, "view recorded", nil)
 }
 
 func (h *Handler) deleteVisualization(c *gin.Context) {
@@ -203,20 +217,24 @@ func (h *Handler) deleteVisualization(c *gin.Context) {
 
 	templateIDStr := c.Param("id")
 	if templateIDStr == "" {
-		h.log.Error(fmt.Sprintf("%s: %v", op, ErrVisualizationIDMissing))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(ErrVisualizationIDMissing))
 		response.Error(c, http.StatusBadRequest, ErrVisualizationIDMissing.Error(), nil)
 		return
 	}
 
 	templateID, err := uuid.Parse(templateIDStr)
 	if err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
 		response.Error(c, http.StatusBadRequest, ErrInvalidVisualizationID.Error(), err)
 		return
 	}
 
 	if err = h.services.Visualization.Delete(c.Request.Context(), templateID); err != nil {
-		h.log.Error(fmt.Sprintf("%s: %v", op, err))
+		h.requestLog(c).Error("request failed", logger.Op(op), logger.Err(err))
+		if errors.Is(err, repository.ErrVisualizationNotFound) {
+			response.Error(c, http.StatusNotFound, ErrVisualizationNotFound.Error(), nil)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, ErrFailedToDeleteVisualization.Error(), err)
 		return
 	}
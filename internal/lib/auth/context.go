@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Identity describes the authenticated caller of a request.
+// Role is the user's global role, OrgRole the role within the active
+// organization OrgID. MFA is set when the session passed a second factor and
+// AdminRequiresMFA when the active organization enforces 2FA for its admins.
+// Requests made with an API key carry its APIKeyID and Scopes; session tokens
+// carry the SessionVersion of the user they were issued with.
+type Identity struct {
+	UserID           uuid.UUID
+	Username         string
+	Role             string
+	OrgID            uuid.UUID
+	OrgRole          string
+	MFA              bool
+	AdminRequiresMFA bool
+	APIKeyID         uuid.UUID
+	Scopes           []string
+	SessionVersion   int
+}
+
+// MissingMFA reports whether the active organization requires a second
+// factor for admins and this session has not passed one.
+func (i Identity) MissingMFA() bool {
+	return i.AdminRequiresMFA && !i.MFA
+}
+
+type ctxKey struct{}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, identity)
+}
+
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(ctxKey{}).(Identity)
+	return identity, ok
+}
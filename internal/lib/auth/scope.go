@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Scopes an API key can be granted. Session tokens are not scoped.
+const (
+	ScopeRead    = "read"
+	ScopeWrite   = "write"
+	ScopePublish = "publish"
+)
+
+var ErrInsufficientScope = errors.New("API key is missing the required scope")
+
+// IsAPIKey reports whether the request was authenticated with an API key.
+func (i Identity) IsAPIKey() bool {
+	return i.APIKeyID != uuid.Nil
+}
+
+// HasScope reports whether the caller may perform actions covered by scope.
+func (i Identity) HasScope(scope string) bool {
+	return !i.IsAPIKey() || slices.Contains(i.Scopes, scope)
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+const (
+	tokenTypeAccess    = "access"
+	tokenTypeChallenge = "2fa"
+	tokenTypeEmbed     = "embed"
+)
+
+type Claims struct {
+	Type    string            `json:"typ,omitempty"`
+	Role    string            `json:"role,omitempty"`
+	OrgID   string            `json:"org,omitempty"`
+	MFA     bool              `json:"mfa,omitempty"`
+	Session int               `json:"sv,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+	Origins []string          `json:"origins,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Embed is the grant carried by an embed token: read access to a single
+// visualization, optionally with parameter values the embedding page cannot
+// change and the origins allowed to frame it.
+type Embed struct {
+	VisualizationID uuid.UUID
+	OrgID           uuid.UUID
+	Params          map[string]string
+	Origins         []string
+	ExpiresAt       time.Time
+}
+
+type TokenManager struct {
+	secret       []byte
+	ttl          time.Duration
+	challengeTTL time.Duration
+}
+
+func NewTokenManager(secret string, ttl, challengeTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:       []byte(secret),
+		ttl:          ttl,
+		challengeTTL: challengeTTL,
+	}
+}
+
+// Issue signs a new access token for the given user. orgID is the active
+// organization and may be uuid.Nil for users without any membership; mfa
+// records whether the user passed a second factor. The token is only valid
+// while the user's session version stays sessionVersion.
+func (m *TokenManager) Issue(userID uuid.UUID, role string, orgID uuid.UUID, mfa bool, sessionVersion int) (string, error) {
+	const op = "auth.TokenManager.Issue"
+
+	claims := m.claims(tokenTypeAccess, userID, m.ttl)
+	claims.Role = role
+	claims.MFA = mfa
+	claims.Session = sessionVersion
+	if orgID != uuid.Nil {
+		claims.OrgID = orgID.String()
+	}
+
+	token, err := m.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// IssueChallenge signs a short-lived token proving that the user passed the
+// password step of a login that still needs a second factor. It cannot be
+// used as an access token.
+func (m *TokenManager) IssueChallenge(userID uuid.UUID) (string, error) {
+	const op = "auth.TokenManager.IssueChallenge"
+
+	token, err := m.sign(m.claims(tokenTypeChallenge, userID, m.challengeTTL))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// Parse validates an access token and returns the identity it carries.
+func (m *TokenManager) Parse(tokenStr string) (Identity, error) {
+	const op = "auth.TokenManager.Parse"
+
+	claims, err := m.parse(tokenStr)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Tokens issued before the type claim existed carry no type at all.
+	if claims.Type != tokenTypeAccess && claims.Type != "" {
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	identity := Identity{
+		UserID:         userID,
+		Role:           claims.Role,
+		MFA:            claims.MFA,
+		SessionVersion: claims.Session,
+	}
+	if claims.OrgID != "" {
+		if identity.OrgID, err = uuid.Parse(claims.OrgID); err != nil {
+			return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+		}
+	}
+
+	return identity, nil
+}
+
+// ParseChallenge validates a challenge token and returns the user it was issued to.
+func (m *TokenManager) ParseChallenge(tokenStr string) (uuid.UUID, error) {
+	const op = "auth.TokenManager.ParseChallenge"
+
+	claims, err := m.parse(tokenStr)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if claims.Type != tokenTypeChallenge {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	return userID, nil
+}
+
+// IssueEmbed signs an embed token valid for ttl. It cannot be used as an
+// access token.
+func (m *TokenManager) IssueEmbed(embed Embed, ttl time.Duration) (string, time.Time, error) {
+	const op = "auth.TokenManager.IssueEmbed"
+
+	claims := m.claims(tokenTypeEmbed, embed.VisualizationID, ttl)
+	claims.OrgID = embed.OrgID.String()
+	claims.Params = embed.Params
+	claims.Origins = embed.Origins
+
+	token, err := m.sign(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, claims.ExpiresAt.Time, nil
+}
+
+// ParseEmbed validates an embed token and returns the grant it carries.
+func (m *TokenManager) ParseEmbed(tokenStr string) (Embed, error) {
+	const op = "auth.TokenManager.ParseEmbed"
+
+	claims, err := m.parse(tokenStr)
+	if err != nil {
+		return Embed{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if claims.Type != tokenTypeEmbed {
+		return Embed{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	embed := Embed{
+		Params:    claims.Params,
+		Origins:   claims.Origins,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if embed.VisualizationID, err = uuid.Parse(claims.Subject); err != nil {
+		return Embed{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	if embed.OrgID, err = uuid.Parse(claims.OrgID); err != nil {
+		return Embed{}, fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+
+	return embed, nil
+}
+
+func (m *TokenManager) claims(tokenType string, subject uuid.UUID, ttl time.Duration) Claims {
+	now := time.Now()
+	return Claims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   subject.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+}
+
+func (m *TokenManager) sign(claims Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+func (m *TokenManager) parse(tokenStr string) (Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return Claims{}, ErrTokenExpired
+		}
+		return Claims{}, ErrInvalidToken
+	}
+
+	return claims, nil
+}
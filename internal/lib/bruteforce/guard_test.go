@@ -0,0 +1,133 @@
+package bruteforce
+
+import (
+	"context"
+	"testing"
+	"time"
+	"visualizer-go/internal/lib/config"
+)
+
+func newTestGuard(t *testing.T) (*Guard, *time.Time) {
+	t.Helper()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := NewGuard(NewMemoryStore(), config.LoginProtection{
+		FreeAttempts:    2,
+		MaxUserFailures: 6,
+		BaseDelay:       time.Second,
+		MaxDelay:        3 * time.Second,
+		LockoutDuration: time.Minute,
+		ResetAfter:      time.Hour,
+	})
+	g.now = func() time.Time { return now }
+
+	return g, &now
+}
+
+func TestGuardBackoff(t *testing.T) {
+	ctx := context.Background()
+	g, _ := newTestGuard(t)
+	key := g.UserKey("Alice")
+
+	// Failures 1-2 are free, then the delay doubles up to MaxDelay until
+	// MaxUserFailures locks the key.
+	want := []time.Duration{0, 0, time.Second, 2 * time.Second, 3 * time.Second, time.Minute}
+	for i, w := range want {
+		lockedOut, err := g.Fail(ctx, key)
+		if err != nil {
+			t.Fatalf("Fail #%d: %v", i+1, err)
+		}
+		if lockedOut != (i == len(want)-1) {
+			t.Errorf("Fail #%d: lockedOut = %v", i+1, lockedOut)
+		}
+
+		wait, err := g.Check(ctx, key)
+		if err != nil {
+			t.Fatalf("Check #%d: %v", i+1, err)
+		}
+		if wait != w {
+			t.Errorf("after failure #%d: wait = %s, want %s", i+1, wait, w)
+		}
+	}
+}
+
+func TestGuardLockoutExpires(t *testing.T) {
+	ctx := context.Background()
+	g, now := newTestGuard(t)
+	key := g.UserKey("alice")
+
+	for range 6 {
+		if _, err := g.Fail(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*now = now.Add(30 * time.Second)
+	if wait, _ := g.Check(ctx, key); wait != 30*time.Second {
+		t.Fatalf("wait = %s, want 30s", wait)
+	}
+
+	*now = now.Add(30 * time.Second)
+	if wait, _ := g.Check(ctx, key); wait != 0 {
+		t.Fatalf("wait after lockout = %s, want 0", wait)
+	}
+}
+
+func TestGuardResetAfter(t *testing.T) {
+	ctx := context.Background()
+	g, now := newTestGuard(t)
+	key := g.UserKey("alice")
+
+	for range 3 {
+		if _, err := g.Fail(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A failure after ResetAfter starts a new count, so it is free again.
+	*now = now.Add(time.Hour + time.Second)
+	if _, err := g.Fail(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := g.Check(ctx, key); wait != 0 {
+		t.Fatalf("wait = %s, want 0", wait)
+	}
+}
+
+func TestGuardReset(t *testing.T) {
+	ctx := context.Background()
+	g, _ := newTestGuard(t)
+	key := g.UserKey("alice")
+
+	for range 4 {
+		if _, err := g.Fail(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := g.Reset(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := g.Check(ctx, key); wait != 0 {
+		t.Fatalf("wait after Reset = %s, want 0", wait)
+	}
+}
+
+func TestGuardCheckReportsLongestWait(t *testing.T) {
+	ctx := context.Background()
+	g, _ := newTestGuard(t)
+	userKey, ipKey := g.UserKey("alice"), g.IPKey("192.0.2.1")
+
+	for range 3 {
+		if _, err := g.Fail(ctx, userKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wait, err := g.Check(ctx, userKey, ipKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait != time.Second {
+		t.Fatalf("wait = %s, want 1s", wait)
+	}
+}
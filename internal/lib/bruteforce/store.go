@@ -0,0 +1,89 @@
+package bruteforce
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Attempt is the failure state tracked for a single key.
+type Attempt struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// LockFunc returns how long a key must wait after its n-th consecutive failure.
+type LockFunc func(failures int) time.Duration
+
+// Store persists failure counters. MemoryStore is enough for a single
+// instance; multi-instance deployments need a shared store.
+type Store interface {
+	Get(ctx context.Context, key string) (Attempt, error)
+	// Fail increments the counter for key, restarting it when the previous
+	// failure happened before now-resetAfter, and sets the lock from lockFor.
+	Fail(ctx context.Context, key string, now time.Time, resetAfter time.Duration, lockFor LockFunc) (Attempt, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]Attempt
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: make(map[string]Attempt)}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attempts[key], nil
+}
+
+func (s *MemoryStore) Fail(_ context.Context, key string, now time.Time, resetAfter time.Duration, lockFor LockFunc) (Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now, resetAfter)
+
+	a := s.attempts[key]
+	if now.Sub(a.LastFailureAt) > resetAfter {
+		a = Attempt{}
+	}
+
+	a.Failures++
+	a.LastFailureAt = now
+	if lock := lockFor(a.Failures); lock > 0 {
+		a.LockedUntil = now.Add(lock)
+	}
+
+	s.attempts[key] = a
+
+	return a, nil
+}
+
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+
+	return nil
+}
+
+// prune forgets keys whose failures have expired so the map stays bounded.
+func (s *MemoryStore) prune(now time.Time, resetAfter time.Duration) {
+	const pruneThreshold = 10_000
+
+	if len(s.attempts) < pruneThreshold {
+		return
+	}
+
+	for key, a := range s.attempts {
+		if now.Sub(a.LastFailureAt) > resetAfter && now.After(a.LockedUntil) {
+			delete(s.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package clientip
+
+import "context"
+
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx carrying the caller's IP address.
+func WithContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, ip)
+}
+
+// FromContext returns the IP address stored in ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(ctxKey{}).(string)
+	return ip
+}
@@ -2,59 +2,305 @@ package config
 
 import (
 	"fmt"
-	"github.com/ilyakaznacheev/cleanenv"
+	"io"
 	"os"
+	"reflect"
 	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
 )
 
 type (
+	// Server configures the HTTP server. Metrics are served on MetricsPort
+	// when it is set; otherwise /metrics is served on the main port and
+	// requires MetricsToken as a bearer token. On shutdown, readiness fails
+	// for DrainDelay before the listener closes so load balancers can stop
+	// routing traffic to the instance.
 	Server struct {
-		Host               string        `yaml:"host"`
-		Port               string        `yaml:"port"`
-		ReadTimeout        time.Duration `yaml:"readTimeout"`
-		WriteTimeout       time.Duration `yaml:"writeTimeout"`
-		MaxHeaderMegabytes int           `yaml:"maxHeaderBytes"`
+		Host               string        `yaml:"host" env:"HOST"`
+		Port               string        `yaml:"port" env:"PORT"`
+		ReadTimeout        time.Duration `yaml:"readTimeout" env:"READ_TIMEOUT"`
+		WriteTimeout       time.Duration `yaml:"writeTimeout" env:"WRITE_TIMEOUT"`
+		MaxHeaderMegabytes int           `yaml:"maxHeaderBytes" env:"MAX_HEADER_BYTES"`
+		MetricsPort        string        `yaml:"metricsPort" env:"METRICS_PORT"`
+		MetricsToken       string        `yaml:"metricsToken" env:"METRICS_TOKEN" secret:"true"`
+		DrainDelay         time.Duration `yaml:"drainDelay" env:"DRAIN_DELAY"`
+		// TrustedProxies lists the IPs and CIDRs whose X-Forwarded-For is
+		// believed. Unset trusts none, so the client IP is the peer address.
+		TrustedProxies []string `yaml:"trustedProxies" env:"TRUSTED_PROXIES"`
 	}
 
+	// Database configures the connection pool. StatementTimeout is enforced
+	// by the server for every statement; at startup, connecting is retried
+	// with backoff for up to StartupTimeout.
 	Database struct {
-		Host     string `yaml:"host"`
-		Port     string `yaml:"port"`
-		Username string `yaml:"username"`
-		Password string `yaml:"password"`
-		DBName   string `yaml:"dbName"`
-		SSLMode  string `yaml:"SSLMode"`
+		Host             string        `yaml:"host" env:"HOST"`
+		Port             string        `yaml:"port" env:"PORT"`
+		Username         string        `yaml:"username" env:"USERNAME"`
+		Password         string        `yaml:"password" env:"PASSWORD" secret:"true"`
+		DBName           string        `yaml:"dbName" env:"DB_NAME"`
+		SSLMode          string        `yaml:"SSLMode" env:"SSL_MODE"`
+		MaxOpenConns     int           `yaml:"maxOpenConns" env:"MAX_OPEN_CONNS" env-default:"25"`
+		MaxIdleConns     int           `yaml:"maxIdleConns" env:"MAX_IDLE_CONNS" env-default:"10"`
+		ConnMaxLifetime  time.Duration `yaml:"connMaxLifetime" env:"CONN_MAX_LIFETIME" env-default:"30m"`
+		ConnMaxIdleTime  time.Duration `yaml:"connMaxIdleTime" env:"CONN_MAX_IDLE_TIME" env-default:"5m"`
+		ConnectTimeout   time.Duration `yaml:"connectTimeout" env:"CONNECT_TIMEOUT" env-default:"5s"`
+		StatementTimeout time.Duration `yaml:"statementTimeout" env:"STATEMENT_TIMEOUT" env-default:"30s"`
+		StartupTimeout   time.Duration `yaml:"startupTimeout" env:"STARTUP_TIMEOUT" env-default:"1m"`
+	}
+
+	// Replica is an optional read replica, used when Host is set. Connection
+	// settings left empty are taken from Database. After a user changes
+	// something, their reads stay on the primary for StickyWindow so that
+	// replication lag does not hide their own changes. Browsers carry the
+	// write in a cookie signed with jwt.secret, so this holds across
+	// instances.
+	Replica struct {
+		Database     `yaml:",inline"`
+		StickyWindow time.Duration `yaml:"stickyWindow" env:"STICKY_WINDOW" env-default:"5s"`
 	}
 
 	Jwt struct {
-		Secret string `yaml:"secret"`
+		Secret string        `yaml:"secret" env:"SECRET" secret:"true"`
+		TTL    time.Duration `yaml:"ttl" env:"TTL" env-default:"24h"`
+	}
+
+	SMTP struct {
+		Host     string        `yaml:"host" env:"HOST"`
+		Port     string        `yaml:"port" env:"PORT" env-default:"587"`
+		Username string        `yaml:"username" env:"USERNAME"`
+		Password string        `yaml:"password" env:"PASSWORD" secret:"true"`
+		Timeout  time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"10s"`
+	}
+
+	Mail struct {
+		Driver string `yaml:"driver" env:"DRIVER" env-default:"log"`
+		From   string `yaml:"from" env:"FROM" env-default:"no-reply@visualizer.local"`
+		Dir    string `yaml:"dir" env:"DIR"`
+		SMTP   SMTP   `yaml:"smtp" env-prefix:"SMTP_"`
+	}
+
+	Invitations struct {
+		TTL time.Duration `yaml:"ttl" env:"TTL" env-default:"168h"`
+	}
+
+	PasswordReset struct {
+		TTL           time.Duration `yaml:"ttl" env:"TTL" env-default:"1h"`
+		Window        time.Duration `yaml:"window" env:"WINDOW" env-default:"1h"`
+		MaxPerAccount int           `yaml:"maxPerAccount" env:"MAX_PER_ACCOUNT" env-default:"3"`
+		MaxPerIP      int           `yaml:"maxPerIP" env:"MAX_PER_IP" env-default:"10"`
+	}
+
+	LoginProtection struct {
+		Store           string        `yaml:"store" env:"STORE" env-default:"memory"`
+		FreeAttempts    int           `yaml:"freeAttempts" env:"FREE_ATTEMPTS" env-default:"3"`
+		IPFreeAttempts  int           `yaml:"ipFreeAttempts" env:"IP_FREE_ATTEMPTS" env-default:"20"`
+		MaxUserFailures int           `yaml:"maxUserFailures" env:"MAX_USER_FAILURES" env-default:"10"`
+		MaxIPFailures   int           `yaml:"maxIPFailures" env:"MAX_IP_FAILURES" env-default:"100"`
+		BaseDelay       time.Duration `yaml:"baseDelay" env:"BASE_DELAY" env-default:"1s"`
+		MaxDelay        time.Duration `yaml:"maxDelay" env:"MAX_DELAY" env-default:"5m"`
+		LockoutDuration time.Duration `yaml:"lockoutDuration" env:"LOCKOUT_DURATION" env-default:"15m"`
+		ResetAfter      time.Duration `yaml:"resetAfter" env:"RESET_AFTER" env-default:"1h"`
+	}
+
+	TwoFactor struct {
+		Issuer        string        `yaml:"issuer" env:"ISSUER" env-default:"Visualizer"`
+		EncryptionKey string        `yaml:"encryptionKey" env:"ENCRYPTION_KEY" secret:"true"`
+		ChallengeTTL  time.Duration `yaml:"challengeTTL" env:"CHALLENGE_TTL" env-default:"5m"`
+	}
+
+	// OIDC configures single sign-on. GroupRoles maps IdP group names to
+	// user roles; users without a mapped group get DefaultRole.
+	OIDC struct {
+		Enabled      bool              `yaml:"enabled" env:"ENABLED"`
+		Issuer       string            `yaml:"issuer" env:"ISSUER"`
+		ClientID     string            `yaml:"clientID" env:"CLIENT_ID"`
+		ClientSecret string            `yaml:"clientSecret" env:"CLIENT_SECRET" secret:"true"`
+		RedirectURL  string            `yaml:"redirectURL" env:"REDIRECT_URL"`
+		Scopes       []string          `yaml:"scopes" env:"SCOPES" env-default:"openid,profile,email"`
+		GroupsClaim  string            `yaml:"groupsClaim" env:"GROUPS_CLAIM" env-default:"groups"`
+		GroupRoles   map[string]string `yaml:"groupRoles" env:"GROUP_ROLES"`
+		DefaultRole  string            `yaml:"defaultRole" env:"DEFAULT_ROLE" env-default:"viewer"`
+		// DefaultOrgID is the organization users provisioned on first sign-on
+		// join, with their mapped role. Without it they start with none.
+		DefaultOrgID string `yaml:"defaultOrgID" env:"DEFAULT_ORG_ID"`
+	}
+
+	// APIKeys bounds the lifetime of personal API keys. Keys created without
+	// an expiry get DefaultTTL.
+	APIKeys struct {
+		DefaultTTL time.Duration `yaml:"defaultTTL" env:"DEFAULT_TTL" env-default:"2160h"`
+		MaxTTL     time.Duration `yaml:"maxTTL" env:"MAX_TTL" env-default:"8760h"`
+	}
+
+	// Embed bounds the lifetime of signed embed tokens.
+	Embed struct {
+		DefaultTTL time.Duration `yaml:"defaultTTL" env:"DEFAULT_TTL" env-default:"15m"`
+		MaxTTL     time.Duration `yaml:"maxTTL" env:"MAX_TTL" env-default:"24h"`
+	}
+
+	// Scheduler applies scheduled publishes and unpublishes. Every instance
+	// may run it; due rows are claimed with row locks. Enabled has no default
+	// because defaults also replace an explicit false.
+	Scheduler struct {
+		Enabled   bool          `yaml:"enabled" env:"ENABLED"`
+		Interval  time.Duration `yaml:"interval" env:"INTERVAL" env-default:"30s"`
+		BatchSize int           `yaml:"batchSize" env:"BATCH_SIZE" env-default:"100"`
+	}
+
+	// Analytics configures view tracking. Viewers are identified by a keyed
+	// hash of their IPv4 address or IPv6 /64; views by the same viewer within
+	// DedupWindow count once. Pages reporting their own views may do so at
+	// most TrackPerIP times per TrackWindow from one network.
+	Analytics struct {
+		ViewerHashKey string        `yaml:"viewerHashKey" env:"VIEWER_HASH_KEY" secret:"true"`
+		DedupWindow   time.Duration `yaml:"dedupWindow" env:"DEDUP_WINDOW" env-default:"30m"`
+		MaxBuckets    int           `yaml:"maxBuckets" env:"MAX_BUCKETS" env-default:"1000"`
+		TrackPerIP    int           `yaml:"trackPerIP" env:"TRACK_PER_IP" env-default:"60"`
+		TrackWindow   time.Duration `yaml:"trackWindow" env:"TRACK_WINDOW" env-default:"1m"`
+	}
+
+	// ViewCounter buffers view counts in memory. At most MaxPending views
+	// are lost if the process crashes, and while the database is failing
+	// further views are dropped instead of buffered.
+	ViewCounter struct {
+		FlushInterval time.Duration `yaml:"flushInterval" env:"FLUSH_INTERVAL" env-default:"5s"`
+		FlushTimeout  time.Duration `yaml:"flushTimeout" env:"FLUSH_TIMEOUT" env-default:"5s"`
+		MaxPending    int           `yaml:"maxPending" env:"MAX_PENDING" env-default:"1000"`
+	}
+
+	// Tracing selects where spans are exported: "none", "stdout" for local
+	// runs, or "otlp" to send them to Endpoint over OTLP/HTTP. SampleRatio
+	// has no default because defaults also replace an explicit 0.
+	Tracing struct {
+		Exporter    string            `yaml:"exporter" env:"EXPORTER" env-default:"none"`
+		Endpoint    string            `yaml:"endpoint" env:"ENDPOINT" env-default:"http://localhost:4318"`
+		Headers     map[string]string `yaml:"headers" env:"HEADERS" secret:"true"`
+		ServiceName string            `yaml:"serviceName" env:"SERVICE_NAME" env-default:"visualizer"`
+		SampleRatio float64           `yaml:"sampleRatio" env:"SAMPLE_RATIO"`
+	}
+
+	// Health bounds each readiness check.
+	Health struct {
+		CheckTimeout time.Duration `yaml:"checkTimeout" env:"CHECK_TIMEOUT" env-default:"2s"`
+	}
+
+	// ShareCache keeps public share responses in memory. Changes made through
+	// this instance drop its entries at once; TTL bounds how long other
+	// instances may serve the previous version. MaxAge and SharedMaxAge are
+	// sent as max-age and s-maxage, letting browsers and a CDN reuse a
+	// response without asking again. They have no default because defaults
+	// also replace an explicit zero, which makes every request revalidate.
+	ShareCache struct {
+		Size         int           `yaml:"size" env:"SIZE" env-default:"1000"`
+		TTL          time.Duration `yaml:"ttl" env:"TTL" env-default:"1m"`
+		MaxAge       time.Duration `yaml:"maxAge" env:"MAX_AGE"`
+		SharedMaxAge time.Duration `yaml:"sharedMaxAge" env:"SHARED_MAX_AGE"`
 	}
 
 	Config struct {
-		Env      string   `yaml:"env" env-default:"local"`
-		Origin   string   `yaml:"origin"`
-		Server   Server   `yaml:"server"`
-		Database Database `yaml:"database"`
-		Jwt      Jwt      `yaml:"jwt"`
+		Env             string          `yaml:"env" env:"APP_ENV" env-default:"local"`
+		Origin          string          `yaml:"origin" env:"ORIGIN"`
+		Server          Server          `yaml:"server" env-prefix:"SERVER_"`
+		Database        Database        `yaml:"database" env-prefix:"DATABASE_"`
+		Replica         Replica         `yaml:"replica" env-prefix:"REPLICA_"`
+		Jwt             Jwt             `yaml:"jwt" env-prefix:"JWT_"`
+		Mail            Mail            `yaml:"mail" env-prefix:"MAIL_"`
+		Invitations     Invitations     `yaml:"invitations" env-prefix:"INVITATIONS_"`
+		PasswordReset   PasswordReset   `yaml:"passwordReset" env-prefix:"PASSWORD_RESET_"`
+		LoginProtection LoginProtection `yaml:"loginProtection" env-prefix:"LOGIN_PROTECTION_"`
+		TwoFactor       TwoFactor       `yaml:"twoFactor" env-prefix:"TWO_FACTOR_"`
+		OIDC            OIDC            `yaml:"oidc" env-prefix:"OIDC_"`
+		APIKeys         APIKeys         `yaml:"apiKeys" env-prefix:"API_KEYS_"`
+		Embed           Embed           `yaml:"embed" env-prefix:"EMBED_"`
+		Scheduler       Scheduler       `yaml:"scheduler" env-prefix:"SCHEDULER_"`
+		Analytics       Analytics       `yaml:"analytics" env-prefix:"ANALYTICS_"`
+		ViewCounter     ViewCounter     `yaml:"viewCounter" env-prefix:"VIEW_COUNTER_"`
+		Tracing         Tracing         `yaml:"tracing" env-prefix:"TRACING_"`
+		Health          Health          `yaml:"health" env-prefix:"HEALTH_"`
+		ShareCache      ShareCache      `yaml:"shareCache" env-prefix:"SHARE_CACHE_"`
 	}
 )
 
-func MustLoad() *Config {
-	appEnv := os.Getenv("APP_ENV")
-	if appEnv == "" {
-		appEnv = "local" // заменить на  local
-	}
+const (
+	EnvLocal = "local"
+	EnvDev   = "dev"
+	EnvProd  = "prod"
+)
 
-	configPath := fmt.Sprintf("./configs/%s.yaml", appEnv)
+// Load reads the config file at path, then applies environment variables and
+// *_FILE secrets on top of it. Without a path it uses CONFIG_PATH, falling
+// back to ./configs/<APP_ENV>.yaml. The result is not validated.
+func Load(path string) (*Config, error) {
+	const op = "config.Load"
+
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		appEnv := os.Getenv("APP_ENV")
+		if appEnv == "" {
+			appEnv = EnvLocal
+		}
+		path = fmt.Sprintf("./configs/%s.yaml", appEnv)
+	}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		panic("config file does not exist: " + configPath)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%s: config file %s: %w", op, path, err)
 	}
 
 	var cfg Config
 
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		panic("can not load config file: " + err.Error())
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", op, path, err)
 	}
 
-	return &cfg
+	if err := readSecretFiles(reflect.ValueOf(&cfg).Elem(), ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cfg.Replica.inherit(cfg.Database)
+
+	return &cfg, nil
+}
+
+// inherit fills the connection settings the replica leaves empty from the
+// primary, which usually shares credentials and database name.
+func (r *Replica) inherit(primary Database) {
+	if r.Host == "" {
+		return
+	}
+	for _, field := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&r.Port, primary.Port},
+		{&r.Username, primary.Username},
+		{&r.Password, primary.Password},
+		{&r.DBName, primary.DBName},
+		{&r.SSLMode, primary.SSLMode},
+	} {
+		if *field.value == "" {
+			*field.value = field.fallback
+		}
+	}
+}
+
+// Usage wraps usage to also list the environment variables that override
+// the config file.
+func Usage(w io.Writer, usage func()) func() {
+	header := "Environment variables (secrets may also be read from <NAME>_FILE):"
+	return cleanenv.FUsage(w, &Config{}, &header, usage)
+}
+
+// Print writes the effective config as YAML, with secrets redacted.
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
 }
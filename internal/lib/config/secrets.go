@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// redacted replaces secrets when the config is printed.
+const redacted = "[REDACTED]"
+
+// readSecretFiles sets every field tagged secret:"true" whose <ENV>_FILE
+// variable is set to the contents of that file, so secrets can come from
+// mounted files instead of the environment. v must be a struct value and
+// prefix the env prefix of its fields.
+func readSecretFiles(v reflect.Value, prefix string) error {
+	var errs []error
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			errs = append(errs, readSecretFiles(v.Field(i), prefix+field.Tag.Get("env-prefix")))
+			continue
+		}
+		if field.Tag.Get("secret") != "true" || field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		env := prefix + field.Tag.Get("env")
+		path, ok := os.LookupEnv(env + "_FILE")
+		if !ok {
+			continue
+		}
+		if _, ok = os.LookupEnv(env); ok {
+			errs = append(errs, fmt.Errorf("%s and %s_FILE are both set", env, env))
+			continue
+		}
+
+		secret, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s_FILE: %w", env, err))
+			continue
+		}
+		// Editors and `echo` leave a trailing newline that is not part of
+		// the secret.
+		v.Field(i).SetString(strings.TrimRight(string(secret), "\r\n"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the config with every secret that is set
+// replaced, safe to print or log.
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+
+		if field.Type.Kind() == reflect.Struct {
+			redact(value)
+			continue
+		}
+		if field.Tag.Get("secret") != "true" || value.IsZero() {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			value.SetString(redacted)
+		case reflect.Map:
+			// The map is shared with the original config, so it is
+			// replaced rather than modified.
+			masked := reflect.MakeMapWithSize(field.Type, value.Len())
+			for _, key := range value.MapKeys() {
+				masked.SetMapIndex(key, reflect.ValueOf(redacted))
+			}
+			value.Set(masked)
+		}
+	}
+}
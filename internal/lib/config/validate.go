@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// minSecretLength is the shortest secret accepted outside local runs.
+const minSecretLength = 32
+
+// checker collects every configuration problem so they are reported at once
+// instead of one per restart.
+type checker struct {
+	errs []error
+}
+
+func (c *checker) check(ok bool, key, problem string) {
+	if !ok {
+		c.errs = append(c.errs, fmt.Errorf("%s: %s", key, problem))
+	}
+}
+
+func (c *checker) required(value, key string) {
+	c.check(value != "", key, "must be set")
+}
+
+func (c *checker) oneOf(value, key string, allowed ...string) {
+	c.check(slices.Contains(allowed, value), key, fmt.Sprintf("must be one of %q, got %q", allowed, value))
+}
+
+func (c *checker) positive(d time.Duration, key string) {
+	c.check(d > 0, key, "must be a positive duration")
+}
+
+func (c *checker) uuid(value, key string) {
+	_, err := uuid.Parse(value)
+	c.check(err == nil, key, fmt.Sprintf("must be a UUID, got %q", value))
+}
+
+func (c *checker) port(value, key string) {
+	n, err := strconv.Atoi(value)
+	c.check(err == nil && n > 0 && n <= 65535, key, fmt.Sprintf("must be a port number, got %q", value))
+}
+
+// secret requires a secret, and outside local runs a long one, so
+// placeholder values do not reach production.
+func (c *checker) secret(value, key string, strict bool) {
+	c.required(value, key)
+	if strict && value != "" {
+		c.check(len(value) >= minSecretLength, key, fmt.Sprintf("must be at least %d characters long", minSecretLength))
+	}
+}
+
+// database checks the connection settings of the primary or the replica,
+// reported under prefix.
+func (c *checker) database(db Database, prefix string, strict bool) {
+	c.required(db.Host, prefix+".host")
+	c.port(db.Port, prefix+".port")
+	c.required(db.Username, prefix+".username")
+	c.required(db.DBName, prefix+".dbName")
+	if strict {
+		c.required(db.Password, prefix+".password")
+	}
+	c.check(db.MaxOpenConns > 0, prefix+".maxOpenConns", "must be positive")
+	c.check(db.MaxIdleConns > 0 && db.MaxIdleConns <= db.MaxOpenConns, prefix+".maxIdleConns",
+		"must be positive and not above "+prefix+".maxOpenConns")
+	c.positive(db.ConnMaxLifetime, prefix+".connMaxLifetime")
+	c.positive(db.ConnMaxIdleTime, prefix+".connMaxIdleTime")
+	c.check(db.ConnectTimeout >= time.Second, prefix+".connectTimeout", "must be at least 1s")
+	c.positive(db.StatementTimeout, prefix+".statementTimeout")
+	c.positive(db.StartupTimeout, prefix+".startupTimeout")
+	if db.SSLMode != "" {
+		c.oneOf(db.SSLMode, prefix+".SSLMode", "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	}
+}
+
+// Validate checks the settings the application needs to start and reports
+// all problems together, one per line.
+func (c *Config) Validate() error {
+	var v checker
+
+	strict := c.Env != EnvLocal
+
+	v.oneOf(c.Env, "env", EnvLocal, EnvDev, EnvProd)
+	v.required(c.Origin, "origin")
+
+	v.port(c.Server.Port, "server.port")
+	if c.Server.MetricsPort != "" {
+		v.port(c.Server.MetricsPort, "server.metricsPort")
+		v.check(c.Server.MetricsPort != c.Server.Port, "server.metricsPort", "must differ from server.port")
+	}
+	v.positive(c.Server.ReadTimeout, "server.readTimeout")
+	v.positive(c.Server.WriteTimeout, "server.writeTimeout")
+	v.check(c.Server.MaxHeaderMegabytes >= 1 && c.Server.MaxHeaderMegabytes <= 64, "server.maxHeaderBytes",
+		fmt.Sprintf("is in megabytes and must be between 1 and 64, got %d", c.Server.MaxHeaderMegabytes))
+	v.check(c.Server.DrainDelay >= 0, "server.drainDelay", "must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		v.check(cidrErr == nil || net.ParseIP(proxy) != nil, "server.trustedProxies",
+			fmt.Sprintf("must hold IPs or CIDRs, got %q", proxy))
+	}
+
+	v.database(c.Database, "database", strict)
+	if c.Replica.Host != "" {
+		v.database(c.Replica.Database, "replica", strict)
+		v.check(c.Replica.StickyWindow >= 0, "replica.stickyWindow", "must not be negative")
+	}
+
+	v.secret(c.Jwt.Secret, "jwt.secret", strict)
+	v.positive(c.Jwt.TTL, "jwt.ttl")
+
+	v.oneOf(c.Mail.Driver, "mail.driver", "log", "smtp")
+	v.required(c.Mail.From, "mail.from")
+	if c.Mail.Driver == "smtp" {
+		v.required(c.Mail.SMTP.Host, "mail.smtp.host")
+		v.port(c.Mail.SMTP.Port, "mail.smtp.port")
+		v.positive(c.Mail.SMTP.Timeout, "mail.smtp.timeout")
+	}
+
+	v.positive(c.Invitations.TTL, "invitations.ttl")
+
+	v.positive(c.PasswordReset.TTL, "passwordReset.ttl")
+	v.positive(c.PasswordReset.Window, "passwordReset.window")
+	v.check(c.PasswordReset.MaxPerAccount > 0, "passwordReset.maxPerAccount", "must be positive")
+	v.check(c.PasswordReset.MaxPerIP > 0, "passwordReset.maxPerIP", "must be positive")
+
+	v.oneOf(c.LoginProtection.Store, "loginProtection.store", "memory", "postgres")
+	v.positive(c.LoginProtection.BaseDelay, "loginProtection.baseDelay")
+	v.check(c.LoginProtection.MaxDelay >= c.LoginProtection.BaseDelay, "loginProtection.maxDelay", "must not be shorter than loginProtection.baseDelay")
+	v.positive(c.LoginProtection.LockoutDuration, "loginProtection.lockoutDuration")
+
+	v.secret(c.TwoFactor.EncryptionKey, "twoFactor.encryptionKey", strict)
+	v.positive(c.TwoFactor.ChallengeTTL, "twoFactor.challengeTTL")
+
+	if c.OIDC.Enabled {
+		v.required(c.OIDC.Issuer, "oidc.issuer")
+		v.required(c.OIDC.ClientID, "oidc.clientID")
+		v.required(c.OIDC.ClientSecret, "oidc.clientSecret")
+		v.required(c.OIDC.RedirectURL, "oidc.redirectURL")
+		if c.OIDC.DefaultOrgID != "" {
+			v.uuid(c.OIDC.DefaultOrgID, "oidc.defaultOrgID")
+		}
+	}
+
+	v.positive(c.APIKeys.DefaultTTL, "apiKeys.defaultTTL")
+	v.check(c.APIKeys.MaxTTL >= c.APIKeys.DefaultTTL, "apiKeys.maxTTL", "must not be shorter than apiKeys.defaultTTL")
+
+	v.positive(c.Embed.DefaultTTL, "embed.defaultTTL")
+	v.check(c.Embed.MaxTTL >= c.Embed.DefaultTTL, "embed.maxTTL", "must not be shorter than embed.defaultTTL")
+
+	if c.Scheduler.Enabled {
+		v.positive(c.Scheduler.Interval, "scheduler.interval")
+		v.check(c.Scheduler.BatchSize > 0, "scheduler.batchSize", "must be positive")
+	}
+
+	v.secret(c.Analytics.ViewerHashKey, "analytics.viewerHashKey", strict)
+	v.check(c.Analytics.DedupWindow >= 0, "analytics.dedupWindow", "must not be negative")
+	v.check(c.Analytics.MaxBuckets > 0, "analytics.maxBuckets", "must be positive")
+	v.check(c.Analytics.TrackPerIP > 0, "analytics.trackPerIP", "must be positive")
+	v.positive(c.Analytics.TrackWindow, "analytics.trackWindow")
+
+	v.positive(c.ViewCounter.FlushInterval, "viewCounter.flushInterval")
+	v.positive(c.ViewCounter.FlushTimeout, "viewCounter.flushTimeout")
+	v.check(c.ViewCounter.MaxPending > 0, "viewCounter.maxPending", "must be positive")
+
+	v.oneOf(c.Tracing.Exporter, "tracing.exporter", "none", "stdout", "otlp")
+	if c.Tracing.Exporter == "otlp" {
+		v.required(c.Tracing.Endpoint, "tracing.endpoint")
+	}
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio", "must be between 0 and 1")
+
+	v.positive(c.Health.CheckTimeout, "health.checkTimeout")
+
+	v.check(c.ShareCache.Size > 0, "shareCache.size", "must be positive")
+	v.positive(c.ShareCache.TTL, "shareCache.ttl")
+	v.check(c.ShareCache.MaxAge >= 0, "shareCache.maxAge", "must not be negative")
+	v.check(c.ShareCache.SharedMaxAge >= 0, "shareCache.sharedMaxAge", "must not be negative")
+
+	return errors.Join(v.errs...)
+}
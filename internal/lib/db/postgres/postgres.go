@@ -1,10 +1,23 @@
 package postgres
 
 import (
+	"context"
+	"database/sql/driver"
+	"errors"
 	"fmt"
-	"github.com/jmoiron/sqlx"
 	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
 	"visualizer-go/internal/lib/config"
+
+	"github.com/XSAM/otelsql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -13,6 +26,14 @@ const (
 	VisualizationTable = "visualizations"
 )
 
+const driverName = "postgres"
+
+// Bounds of the delay between connection attempts at startup.
+const (
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 10 * time.Second
+)
+
 type Postgres struct {
 	Host     string
 	Port     string
@@ -22,25 +43,125 @@ type Postgres struct {
 	SSLMode  string
 }
 
-// TODO:
-// Separate constructor logic, connect, ping, etc...
+// Connect opens the connection pool and waits until the database answers,
+// retrying with exponential backoff for up to cfg.StartupTimeout so the
+// application may start before the database does. Errors the server reports
+// itself, such as a wrong password, fail immediately.
+//
+// Once connected, the pool replaces broken connections on its own: the
+// driver marks them bad and database/sql dials new ones, so the application
+// recovers from a database restart without intervention.
+func Connect(ctx context.Context, log *slog.Logger, cfg config.Database) (*sqlx.DB, error) {
+	const op = "postgres.Connect"
+
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("op", op), slog.String("dsn", dataSourceName(cfg).Redacted()))
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.StartupTimeout)
+	defer cancel()
+
+	delay := minRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		if !retryable(err) {
+			db.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		// Jitter keeps instances restarted together from retrying in step.
+		wait := delay/2 + rand.N(delay/2)
+		log.Warn("database is not reachable, retrying...", slog.Int("attempt", attempt),
+			slog.Duration("retryIn", wait), slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("%s: database not reachable after %d attempts: %w", op, attempt, err)
+		case <-time.After(wait):
+		}
 
-func MustConnect(log *slog.Logger, cfg config.Database) *sqlx.DB {
-	var driverName = "postgres"
-	var dataSourceName = fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode)
+		delay = min(delay*2, maxRetryDelay)
+	}
+
+	log.Info("postgres connection successfully established")
+
+	return db, nil
+}
+
+// Open configures the connection pool without connecting; connections are
+// dialled on first use.
+func Open(cfg config.Database) (*sqlx.DB, error) {
+	const op = "postgres.Open"
 
-	db, err := sqlx.Open(driverName, dataSourceName)
+	dsn := dataSourceName(cfg)
 
+	// Every statement becomes a child span of the repository method that ran
+	// it, with the SQL as db.statement. Statements outside a trace are not
+	// recorded.
+	sqlDB, err := otelsql.Open(driverName, dsn.String(),
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}))
 	if err != nil {
-		panic("failed to connect to database: " + err.Error())
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err = db.Ping(); err != nil {
-		panic("failed to ping database: " + err.Error())
+	db := sqlx.NewDb(sqlDB, driverName)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// dataSourceName builds a connection URL, which escapes credentials that a
+// key=value string would break on, such as spaces or quotes in a password.
+// Parameters lib/pq does not know are sent to the server as run-time
+// settings, which is how statement_timeout applies to every connection.
+func dataSourceName(cfg config.Database) *url.URL {
+	query := url.Values{}
+	if cfg.SSLMode != "" {
+		query.Set("sslmode", cfg.SSLMode)
 	}
+	query.Set("connect_timeout", strconv.Itoa(int(cfg.ConnectTimeout.Seconds())))
+	query.Set("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
 
-	log.Info("postgres connection successfully established")
+	return &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:     "/" + cfg.DBName,
+		RawQuery: query.Encode(),
+	}
+}
 
-	return db
+// retryable reports whether connecting may succeed later. Errors sent by the
+// server mean it is up and rejected the connection, which retrying does not
+// fix, except while it is starting or out of connection slots.
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return true
+	}
+
+	switch pqErr.Code.Class() {
+	case "53", "57": // insufficient_resources, operator_intervention
+		return true
+	default:
+		return false
+	}
 }
@@ -0,0 +1,217 @@
+// Package replica routes read-only queries to a read replica.
+package replica
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	targetReplica  = "replica"
+	targetPrimary  = "primary"
+	targetFallback = "fallback"
+)
+
+type ctxKey struct{}
+
+type markerKey struct{}
+
+// recentWrite is a write reported by a verified marker.
+type recentWrite struct {
+	userID uuid.UUID
+	at     time.Time
+}
+
+// WithPrimary returns a copy of ctx whose reads all go to the primary, for
+// requests that read state they are about to change.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKey{}, true)
+}
+
+func primaryRequired(ctx context.Context) bool {
+	required, _ := ctx.Value(ctxKey{}).(bool)
+	return required
+}
+
+// Router sends reads to the replica when there is one. Replicas lag behind
+// the primary, so a user who changed something reads from the primary for
+// the sticky window afterwards. Writes are tracked in memory and handed to
+// the client as a signed marker, so the window also holds when the user's
+// next request reaches another instance.
+type Router struct {
+	log     *slog.Logger
+	primary *sqlx.DB
+	replica *sqlx.DB
+	window  time.Duration
+	key     []byte
+
+	mu        sync.Mutex
+	writes    map[uuid.UUID]time.Time
+	lastSweep time.Time
+}
+
+// NewRouter returns a router over primary and replica. Without a replica
+// every read goes to the primary. key signs the write markers; every
+// instance must share it.
+func NewRouter(log *slog.Logger, primary, replica *sqlx.DB, window time.Duration, key string) *Router {
+	return &Router{
+		log:     log,
+		primary: primary,
+		replica: replica,
+		window:  window,
+		key:     []byte(key),
+		writes:  make(map[uuid.UUID]time.Time),
+	}
+}
+
+// StickyWindow returns how long reads stay on the primary after a write.
+func (r *Router) StickyWindow() time.Duration {
+	return r.window
+}
+
+// MarkWrite sends the reads of userID to the primary for the sticky window.
+// It returns a marker of the write for the client to send back with its
+// next requests, or "" when there is no replica.
+func (r *Router) MarkWrite(userID uuid.UUID) string {
+	if r.replica == nil || r.window <= 0 {
+		return ""
+	}
+
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.writes[userID] = now
+
+	// Entries expire after one window, so sweeping once per window keeps
+	// the map at the users who wrote recently.
+	if now.Sub(r.lastSweep) > r.window {
+		for id, at := range r.writes {
+			if now.Sub(at) > r.window {
+				delete(r.writes, id)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	payload := userID.String() + "." + strconv.FormatInt(now.UnixMilli(), 10)
+	return payload + "." + r.sign(payload)
+}
+
+// WithMarker returns a copy of ctx that carries the write reported by
+// marker, when it is authentic and still inside the sticky window.
+func (r *Router) WithMarker(ctx context.Context, marker string) context.Context {
+	if r.replica == nil || marker == "" {
+		return ctx
+	}
+
+	payload, sig, ok := cutLast(marker, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(r.sign(payload))) {
+		return ctx
+	}
+
+	id, ms, ok := strings.Cut(payload, ".")
+	if !ok {
+		return ctx
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return ctx
+	}
+	unixMilli, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return ctx
+	}
+
+	at := time.UnixMilli(unixMilli)
+	if time.Since(at) > r.window {
+		return ctx
+	}
+
+	return context.WithValue(ctx, markerKey{}, recentWrite{userID: userID, at: at})
+}
+
+// sign authenticates a marker payload. The prefix keeps the MAC apart from
+// other uses of the same key.
+func (r *Router) sign(payload string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte("replica.marker\x00"))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// sticky reports whether the reads of ctx must see the latest writes.
+func (r *Router) sticky(ctx context.Context) bool {
+	if primaryRequired(ctx) {
+		return true
+	}
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	if w, ok := ctx.Value(markerKey{}).(recentWrite); ok && w.userID == identity.UserID && time.Since(w.at) <= r.window {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	at, ok := r.writes[identity.UserID]
+	return ok && time.Since(at) <= r.window
+}
+
+// Read runs query on the replica and, if that fails, once more on the
+// primary. A missing row also falls back, since it may simply not have
+// been replicated yet. query must not keep state between the two calls.
+func Read[T any](ctx context.Context, r *Router, op string, query func(db *sqlx.DB) (T, error)) (T, error) {
+	span := trace.SpanFromContext(ctx)
+
+	if r.replica == nil || r.sticky(ctx) {
+		metrics.RoutedReads.WithLabelValues(targetPrimary).Inc()
+		span.SetAttributes(attribute.String("db.target", targetPrimary))
+		return query(r.primary)
+	}
+
+	result, err := query(r.replica)
+	if err == nil || errors.Is(err, context.Canceled) {
+		metrics.RoutedReads.WithLabelValues(targetReplica).Inc()
+		span.SetAttributes(attribute.String("db.target", targetReplica))
+		return result, err
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx, r.log).Warn("replica read failed, falling back to primary",
+			logger.Op(op), logger.Err(err))
+	}
+
+	metrics.RoutedReads.WithLabelValues(targetFallback).Inc()
+	span.SetAttributes(attribute.String("db.target", targetFallback))
+	return query(r.primary)
+}
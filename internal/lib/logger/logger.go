@@ -1 +1,32 @@
 package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx carrying the request-scoped logger.
+func WithContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the logger stored in ctx, or fallback when ctx does not
+// belong to a request.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return log
+	}
+	return fallback
+}
+
+// Op returns the attribute naming the operation that logged a record.
+func Op(op string) slog.Attr {
+	return slog.String("op", op)
+}
+
+// Err returns the attribute carrying err.
+func Err(err error) slog.Attr {
+	return slog.String("error", err.Error())
+}
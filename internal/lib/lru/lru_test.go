@@ -0,0 +1,114 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestCache(size int, ttl time.Duration) (*Cache[string, int], *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := New[string, int](size, ttl)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c, _ := newTestCache(2, time.Minute)
+
+	c.Add("a", 1)
+	c.Add("b", 2)
+	// Reading a makes b the least recently used entry.
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v", v, ok)
+	}
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Errorf("Get(%s) = %d, %v, want %d", key, v, ok, want)
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+}
+
+func TestCacheAddReplaces(t *testing.T) {
+	c, _ := newTestCache(2, time.Minute)
+
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Add("a", 10)
+	// Replacing a made it the most recent entry, so adding c evicts b.
+	c.Add("c", 3)
+
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Errorf("Get(a) = %d, %v, want 10", v, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	c, now := newTestCache(2, time.Minute)
+
+	c.Add("a", 1)
+
+	*now = now.Add(time.Minute - time.Nanosecond)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("entry expired before its TTL")
+	}
+
+	*now = now.Add(time.Nanosecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("entry served after its TTL")
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len = %d, want expired entry removed", n)
+	}
+}
+
+func TestCacheAddRenewsTTL(t *testing.T) {
+	c, now := newTestCache(2, time.Minute)
+
+	c.Add("a", 1)
+	*now = now.Add(30 * time.Second)
+	c.Add("a", 2)
+	*now = now.Add(45 * time.Second)
+
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Fatalf("Get(a) = %d, %v, want 2", v, ok)
+	}
+}
+
+func TestCacheRemove(t *testing.T) {
+	c, _ := newTestCache(4, time.Minute)
+
+	for i, key := range []string{"a", "b", "c", "d"} {
+		c.Add(key, i)
+	}
+
+	c.Remove("a")
+	c.Remove("missing")
+	c.RemoveFunc(func(_ string, v int) bool { return v%2 == 1 })
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Remove kept a")
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("RemoveFunc kept b")
+	}
+	if _, ok := c.Get("d"); ok {
+		t.Error("RemoveFunc kept d")
+	}
+	if v, ok := c.Get("c"); !ok || v != 2 {
+		t.Errorf("Get(c) = %d, %v, want 2", v, ok)
+	}
+	if n := c.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+}
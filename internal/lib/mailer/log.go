@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// LogMailer is a stand-in for local development: it logs that a message was
+// sent and, when dir is set, writes it to a file so links can be copied from
+// there. Bodies carry invitation and reset tokens, so they are never logged.
+type LogMailer struct {
+	log  *slog.Logger
+	from string
+	dir  string
+}
+
+func NewLogMailer(log *slog.Logger, from, dir string) *LogMailer {
+	return &LogMailer{log: log, from: from, dir: dir}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	const op = "mailer.LogMailer.Send"
+
+	log := m.log.With(
+		slog.String("op", op),
+		slog.String("from", m.from),
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+	)
+
+	if m.dir == "" {
+		log.Info("email sent")
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	name := fmt.Sprintf("%s_%s.txt", time.Now().Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	content := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\n\n%s\n", m.from, msg.To, msg.Subject, msg.Body)
+
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email sent", slog.String("file", path))
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"visualizer-go/internal/lib/config"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional emails such as invitations.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func New(log *slog.Logger, cfg config.Mail) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg.From, cfg.SMTP), nil
+	case DriverLog, "":
+		return NewLogMailer(log, cfg.From, cfg.Dir), nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown driver %q", cfg.Driver)
+	}
+}
+
+func MustNew(log *slog.Logger, cfg config.Mail) Mailer {
+	m, err := New(log, cfg)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
@@ -0,0 +1,199 @@
+// Package metrics holds the Prometheus collectors of the application and the
+// registry they are exposed from.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "visualizer"
+
+// Version is set at build time with
+// -ldflags "-X visualizer-go/internal/lib/metrics.Version=<version>".
+var Version = "dev"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	QueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_query_duration_seconds",
+		Help:      "Duration of repository methods, labelled by their op name.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"op"})
+
+	ViewCounterFlushDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "view_counter_flush_duration_seconds",
+		Help:      "Duration of buffered view count flushes by result.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"result"})
+
+	ViewCounterFlushedViews = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "view_counter_flushed_views_total",
+		Help:      "Views written by the buffered view counter.",
+	})
+
+	ViewCounterDroppedViews = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "view_counter_dropped_views_total",
+		Help:      "Views dropped because the buffer was full while flushes were failing.",
+	})
+
+	RoutedReads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_routed_reads_total",
+		Help:      "Replica-eligible reads by the database that served them: replica, primary, or fallback after a replica error.",
+	}, []string{"target"})
+
+	ShareCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "share_cache_lookups_total",
+		Help:      "Public share lookups by result: hit, or miss when the database was queried.",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		QueryDuration,
+		ViewCounterFlushDuration,
+		ViewCounterFlushedViews,
+		ViewCounterDroppedViews,
+		RoutedReads,
+		ShareCacheLookups,
+		buildInfo(),
+	)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// MustRegister adds collectors that depend on runtime state, such as the
+// database pool.
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+// ObserveQuery records how long the repository method op took. Use it as
+// defer metrics.ObserveQuery(op, time.Now()).
+func ObserveQuery(op string, start time.Time) {
+	QueryDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// DBStats exposes the pool statistics of db.
+func DBStats(db *sql.DB, name string) prometheus.Collector {
+	return collectors.NewDBStatsCollector(db, name)
+}
+
+// GaugeFunc exposes a value read at scrape time.
+func GaugeFunc(name, help string, fn func() float64) prometheus.Collector {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, fn)
+}
+
+// EntityCounts are the business gauges exposed by EntityCollector.
+type EntityCounts struct {
+	Visualizations          int
+	PublishedVisualizations int
+	Templates               int
+	Users                   int
+}
+
+// EntityCollector queries the business gauges on every scrape. A failing
+// query drops the gauges from that scrape instead of failing it.
+type EntityCollector struct {
+	log     *slog.Logger
+	count   func(ctx context.Context) (EntityCounts, error)
+	timeout time.Duration
+
+	visualizations *prometheus.Desc
+	published      *prometheus.Desc
+	templates      *prometheus.Desc
+	users          *prometheus.Desc
+}
+
+func NewEntityCollector(log *slog.Logger, timeout time.Duration, count func(ctx context.Context) (EntityCounts, error)) *EntityCollector {
+	return &EntityCollector{
+		log:            log,
+		count:          count,
+		timeout:        timeout,
+		visualizations: prometheus.NewDesc(namespace+"_visualizations", "Visualizations across all organizations.", nil, nil),
+		published:      prometheus.NewDesc(namespace+"_visualizations_published", "Published visualizations across all organizations.", nil, nil),
+		templates:      prometheus.NewDesc(namespace+"_templates", "Templates across all organizations.", nil, nil),
+		users:          prometheus.NewDesc(namespace+"_users", "Active users.", nil, nil),
+	}
+}
+
+func (ec *EntityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ec.visualizations
+	ch <- ec.published
+	ch <- ec.templates
+	ch <- ec.users
+}
+
+func (ec *EntityCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), ec.timeout)
+	defer cancel()
+
+	counts, err := ec.count(ctx)
+	if err != nil {
+		ec.log.Error("failed to collect entity counts", slog.String("error", err.Error()))
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(ec.visualizations, prometheus.GaugeValue, float64(counts.Visualizations))
+	ch <- prometheus.MustNewConstMetric(ec.published, prometheus.GaugeValue, float64(counts.PublishedVisualizations))
+	ch <- prometheus.MustNewConstMetric(ec.templates, prometheus.GaugeValue, float64(counts.Templates))
+	ch <- prometheus.MustNewConstMetric(ec.users, prometheus.GaugeValue, float64(counts.Users))
+}
+
+// buildInfo is a constant gauge labelled with the version, VCS revision and
+// Go version of the running binary.
+func buildInfo() prometheus.Collector {
+	revision, goVersion := "unknown", "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		goVersion = info.GoVersion
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				revision = setting.Value
+			}
+		}
+	}
+
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "build_info",
+		Help:        "Build information of the running binary; always 1.",
+		ConstLabels: prometheus.Labels{"version": Version, "revision": revision, "goversion": goVersion},
+	})
+	gauge.Set(1)
+	return gauge
+}
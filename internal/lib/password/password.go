@@ -0,0 +1,46 @@
+package password
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is a bcrypt hash at bcrypt.DefaultCost that no real password
+// matches. CompareDummy checks against it so a lookup miss costs as much as a
+// wrong password.
+const dummyHash = "$2a$10$JLPFVgFn1ovdTD5qPJ5ZOOa0CXyZNmvQPY.RqFm4WbJnlMR45zwTe"
+
+// Hash returns the bcrypt hash of plain.
+func Hash(plain string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Compare reports whether plain matches hash. Rows created before passwords
+// were hashed still hold the plain value, so non-bcrypt hashes are compared
+// directly until the user sets a new password.
+func Compare(hash, plain string) bool {
+	if IsLegacy(hash) {
+		trimmed := strings.ReplaceAll(hash, " ", "")
+		return subtle.ConstantTimeCompare([]byte(trimmed), []byte(plain)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil
+}
+
+// IsLegacy reports whether hash is a plain-text password from before hashing was introduced.
+func IsLegacy(hash string) bool {
+	return !strings.HasPrefix(hash, "$2")
+}
+
+// CompareDummy spends the same time as Compare against a real bcrypt hash and
+// always reports false. Use it when the account does not exist, so the
+// response time does not reveal which usernames are registered.
+func CompareDummy(plain string) bool {
+	_ = bcrypt.CompareHashAndPassword([]byte(dummyHash), []byte(plain))
+	return false
+}
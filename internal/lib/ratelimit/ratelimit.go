@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is an in-memory fixed-window rate limiter keyed by arbitrary strings.
+type Limiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow records a hit for key. When the limit is exceeded it returns false
+// together with the time left until the current window resets.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &bucket{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+
+	w.count++
+
+	return true, 0
+}
+
+// prune drops expired windows once the map grows, keeping memory bounded
+// without a background goroutine.
+func (l *Limiter) prune(now time.Time) {
+	const pruneThreshold = 10_000
+
+	if len(l.windows) < pruneThreshold {
+		return
+	}
+
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header used to receive and propagate request IDs.
+const Header = "X-Request-ID"
+
+// maxLength bounds IDs taken from callers, which end up in logs, audit
+// records and exports.
+const maxLength = 128
+
+type ctxKey struct{}
+
+// New generates a fresh request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether id, usually taken from a caller's header, is safe to
+// reuse: 1 to 128 letters, digits and '.', '_', ':' or '-'.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '.', c == '_', c == ':', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithContext returns a copy of ctx carrying the given request ID.
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
@@ -0,0 +1,30 @@
+package requestid
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"", false},
+		{"3f2c9a1e-4b7d-4e0a-9c1f-2d8e6b5a7c90", true},
+		{"edge.lb-01:req_42", true},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+		{"abc def", false},
+		{"abc\r\nX-Injected: 1", false},
+		{"=cmd|' /C calc'!A0", false},
+		{"<script>", false},
+		{"idé", false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(tt.id); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"visualizer-go/internal/lib/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	problemTypeDefault    = "about:blank"
+	problemTypeValidation = "urn:visualizer:problem:validation-error"
+)
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	RequestID     string         `json:"requestId,omitempty"`
+	InvalidParams []InvalidParam `json:"invalidParams,omitempty"`
+}
+
+// InvalidParam describes a single request field that failed binding or validation.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// WantsProblem reports whether the client asked for application/problem+json.
+func WantsProblem(c *gin.Context) bool {
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != ProblemContentType {
+			continue
+		}
+		if q, ok := params["q"]; ok && strings.Trim(q, "0.") == "" {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// WriteProblem writes an application/problem+json response.
+func WriteProblem(c *gin.Context, statusCode int, detail string, err interface{}) {
+	problem := Problem{
+		Type:      problemTypeDefault,
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: requestid.FromContext(c.Request.Context()),
+	}
+
+	if e, ok := err.(error); ok {
+		problem.InvalidParams = InvalidParams(e)
+	}
+	if len(problem.InvalidParams) > 0 {
+		problem.Type = problemTypeValidation
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.Render(statusCode, problemRender{problem})
+}
+
+// InvalidParams extracts field-level details from binding and validation errors.
+// It returns nil for any other kind of error.
+func InvalidParams(err error) []InvalidParam {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		params := make([]InvalidParam, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			params = append(params, InvalidParam{
+				Name:   fieldName(fe),
+				Reason: validationReason(fe),
+			})
+		}
+		return params
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []InvalidParam{{
+			Name:   typeErr.Field,
+			Reason: "must be of type " + typeErr.Type.String(),
+		}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return []InvalidParam{{
+			Name:   "body",
+			Reason: "malformed JSON",
+		}}
+	}
+
+	return nil
+}
+
+// fieldName strips the top-level struct name from the validator namespace,
+// e.g. "UserCreateDto.username" becomes "username".
+func fieldName(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func validationReason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return "is required when " + fe.Param() + " is not set"
+	case "len":
+		return "must be exactly " + fe.Param() + " characters long"
+	case "numeric":
+		return "must contain only digits"
+	case "min":
+		return "must be at least " + fe.Param() + " characters long"
+	case "max":
+		return "must be at most " + fe.Param() + " characters long"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "slug":
+		return "must contain only lowercase letters, digits and dashes"
+	case "maxjsonbytes":
+		return "must be at most " + fe.Param() + " bytes when serialized"
+	case "email":
+		return "must be a valid email address"
+	default:
+		if fe.Param() != "" {
+			return "failed on '" + fe.Tag() + "=" + fe.Param() + "' rule"
+		}
+		return "failed on '" + fe.Tag() + "' rule"
+	}
+}
+
+type problemRender struct {
+	problem Problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ProblemContentType)
+}
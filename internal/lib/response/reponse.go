@@ -15,19 +15,48 @@ type ApiResponse struct {
 }
 
 func Success(c *gin.Context, statusCode int, message string, data interface{}) {
+	SuccessAt(c, statusCode, message, data, time.Now())
+}
+
+// SuccessAt writes a success response stamped with at instead of the current
+// time. Responses carrying an ETag use it so that the body stays the same as
+// long as the data does.
+func SuccessAt(c *gin.Context, statusCode int, message string, data interface{}, at time.Time) {
 	c.JSON(statusCode, ApiResponse{
 		Success:   true,
 		Message:   message,
 		Data:      data,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: at.Format(time.RFC3339),
 	})
 }
 
+// Error writes an error response. Clients that accept application/problem+json
+// get an RFC 7807 problem document, everyone else gets the ApiResponse envelope.
 func Error(c *gin.Context, statusCode int, message string, err interface{}) {
+	if WantsProblem(c) {
+		WriteProblem(c, statusCode, message, err)
+		return
+	}
+
 	c.JSON(statusCode, ApiResponse{
 		Success:   false,
 		Message:   message,
-		Error:     err,
+		Error:     errorDetails(message, err),
 		Timestamp: time.Now().Format(time.RFC3339),
 	})
 }
+
+// errorDetails makes sure raw Go errors are never serialized as-is: they
+// either become a list of invalid fields or fall back to the public message.
+func errorDetails(message string, err interface{}) interface{} {
+	e, ok := err.(error)
+	if !ok {
+		return err
+	}
+
+	if params := InvalidParams(e); params != nil {
+		return params
+	}
+
+	return message
+}
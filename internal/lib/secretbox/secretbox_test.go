@@ -0,0 +1,78 @@
+package secretbox
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	box := MustNew("passphrase")
+
+	for _, plaintext := range []string{"", "JBSWY3DPEHPK3PXP", "ünïcødé"} {
+		sealed, err := box.Seal(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		opened, err := box.Open(sealed)
+		if err != nil {
+			t.Fatalf("Open(Seal(%q)): %v", plaintext, err)
+		}
+		if opened != plaintext {
+			t.Fatalf("Open(Seal(%q)) = %q", plaintext, opened)
+		}
+	}
+}
+
+func TestSealUsesFreshNonce(t *testing.T) {
+	box := MustNew("passphrase")
+
+	a, _ := box.Seal("secret")
+	b, _ := box.Seal("secret")
+	if a == b {
+		t.Fatal("sealing the same plaintext twice produced identical output")
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	box := MustNew("passphrase")
+
+	sealed, err := box.Seal("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+
+	flip := func(i int) string {
+		tampered := append([]byte(nil), raw...)
+		tampered[i] ^= 0x01
+		return base64.StdEncoding.EncodeToString(tampered)
+	}
+
+	tests := map[string]string{
+		"nonce":      flip(0),
+		"ciphertext": flip(box.aead.NonceSize()),
+		"tag":        flip(len(raw) - 1),
+		"truncated":  base64.StdEncoding.EncodeToString(raw[:len(raw)-1]),
+		"too short":  base64.StdEncoding.EncodeToString(raw[:box.aead.NonceSize()-1]),
+		"not base64": "%%%",
+	}
+
+	for name, input := range tests {
+		if _, err := box.Open(input); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("%s: Open error = %v, want ErrInvalidCiphertext", name, err)
+		}
+	}
+}
+
+func TestOpenRejectsOtherKey(t *testing.T) {
+	sealed, err := MustNew("one").Seal("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MustNew("two").Open(sealed); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("Open with other key error = %v, want ErrInvalidCiphertext", err)
+	}
+}
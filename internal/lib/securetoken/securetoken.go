@@ -0,0 +1,29 @@
+package securetoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const tokenBytes = 32
+
+// Generate returns a random URL-safe token together with the hash that
+// should be stored instead of the token itself.
+func Generate() (token string, hash string, err error) {
+	buf := make([]byte, tokenBytes)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+
+	return token, Hash(token), nil
+}
+
+// Hash returns the hex-encoded SHA-256 of token.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
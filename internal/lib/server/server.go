@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 	"visualizer-go/internal/lib/config"
 )
 
@@ -12,6 +13,7 @@ type Server struct {
 	log        *slog.Logger
 	config     config.Server
 	httpServer *http.Server
+	onStop     []func()
 }
 
 func New(log *slog.Logger, config config.Server, handler http.Handler) *Server {
@@ -48,11 +50,30 @@ func (s *Server) Run() error {
 	return nil
 }
 
+// RegisterOnStop registers f to be called as soon as Stop begins, before
+// the drain delay and before the listener is closed.
+func (s *Server) RegisterOnStop(f func()) {
+	s.onStop = append(s.onStop, f)
+}
+
 func (s *Server) Stop(ctx context.Context) error {
 	const op = "server.Shutdown"
 
 	log := s.log.With(slog.String("op", op))
 
+	for _, f := range s.onStop {
+		f()
+	}
+
+	if s.config.DrainDelay > 0 {
+		log.Info("draining http server...", slog.Duration("delay", s.config.DrainDelay))
+
+		select {
+		case <-time.After(s.config.DrainDelay):
+		case <-ctx.Done():
+		}
+	}
+
 	log.Info("stopping http server...")
 
 	return s.httpServer.Shutdown(ctx)
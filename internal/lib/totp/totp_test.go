@@ -0,0 +1,90 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed "12345678901234567890" from RFC 6238 Appendix B.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8-digit codes; 6-digit codes are their last six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestValidateRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		now := time.Unix(v.unix, 0)
+
+		step, ok := Validate(rfcSecret, v.code, now, 0)
+		if !ok {
+			t.Errorf("Validate(%d, %s) = false, want true", v.unix, v.code)
+			continue
+		}
+		if want := v.unix / 30; step != want {
+			t.Errorf("Validate(%d, %s) step = %d, want %d", v.unix, v.code, step, want)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	// 287082 belongs to step 1 (t=30..59).
+	next := time.Unix(60, 0)
+
+	if _, ok := Validate(rfcSecret, "287082", next, 0); ok {
+		t.Fatal("code from previous step accepted without skew")
+	}
+	if step, ok := Validate(rfcSecret, "287082", next, 1); !ok || step != 1 {
+		t.Fatalf("Validate with skew = (%d, %v), want (1, true)", step, ok)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	tests := map[string]struct {
+		secret string
+		code   string
+	}{
+		"wrong code":     {rfcSecret, "287083"},
+		"short code":     {rfcSecret, "28708"},
+		"long code":      {rfcSecret, "94287082"},
+		"invalid secret": {"not base32!", "287082"},
+	}
+
+	for name, tt := range tests {
+		if _, ok := Validate(tt.secret, tt.code, now, 1); ok {
+			t.Errorf("%s: Validate = true, want false", name)
+		}
+	}
+}
+
+func TestValidateNormalizesInput(t *testing.T) {
+	if _, ok := Validate(" gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", " 287082 ", time.Unix(59, 0), 0); !ok {
+		t.Fatal("Validate rejected lower-case secret or padded code")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret is not base32: %v", err)
+	}
+	if len(key) != secretBytes {
+		t.Fatalf("secret has %d bytes, want %d", len(key), secretBytes)
+	}
+}
@@ -0,0 +1,55 @@
+// Package useragent sorts User-Agent headers into coarse classes. Only the
+// class is stored with analytics events, never the raw header.
+package useragent
+
+import "strings"
+
+const (
+	ClassBot     = "bot"
+	ClassMobile  = "mobile"
+	ClassTablet  = "tablet"
+	ClassDesktop = "desktop"
+	ClassOther   = "other"
+)
+
+// botMarkers match crawlers, link unfurlers, monitoring and HTTP libraries.
+var botMarkers = []string{
+	"bot", "crawl", "spider", "slurp", "scrape", "fetch", "preview",
+	"facebookexternalhit", "embedly", "headless", "phantomjs", "lighthouse",
+	"pingdom", "uptime", "monitor", "curl", "wget", "httpie",
+	"python-requests", "python-urllib", "aiohttp", "go-http-client",
+	"java/", "okhttp", "axios", "node-fetch", "libwww", "postman",
+}
+
+// Classify returns the class of ua. An empty header is treated as a bot:
+// every browser sends one.
+func Classify(ua string) string {
+	ua = strings.ToLower(strings.TrimSpace(ua))
+	if ua == "" {
+		return ClassBot
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return ClassBot
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		return ClassTablet
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone"):
+		return ClassMobile
+	case strings.Contains(ua, "windows") || strings.Contains(ua, "macintosh") ||
+		strings.Contains(ua, "x11") || strings.Contains(ua, "cros"):
+		return ClassDesktop
+	default:
+		return ClassOther
+	}
+}
+
+// IsBot reports whether ua belongs to an automated client.
+func IsBot(ua string) bool {
+	return Classify(ua) == ClassBot
+}
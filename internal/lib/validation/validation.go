@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// MustRegister configures gin's validator to report JSON field names and
+// registers the custom rules used by the DTOs.
+func MustRegister() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		panic(errors.New("validation: unexpected validator engine"))
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	// Validate UUIDs as strings so that "required" and "uuid" reject uuid.Nil.
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		id, ok := field.Interface().(uuid.UUID)
+		if !ok || id == uuid.Nil {
+			return ""
+		}
+		return id.String()
+	}, uuid.UUID{})
+
+	if err := v.RegisterValidation("maxjsonbytes", maxJSONBytes); err != nil {
+		panic(err)
+	}
+
+	if err := v.RegisterValidation("slug", slug); err != nil {
+		panic(err)
+	}
+}
+
+var slugRegexp = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// slug accepts lowercase letters, digits and single dashes between them.
+func slug(fl validator.FieldLevel) bool {
+	return slugRegexp.MatchString(fl.Field().String())
+}
+
+// maxJSONBytes checks that the field serializes to at most param bytes of JSON.
+func maxJSONBytes(fl validator.FieldLevel) bool {
+	limit, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return false
+	}
+
+	if !fl.Field().IsValid() {
+		return true
+	}
+
+	raw, err := json.Marshal(fl.Field().Interface())
+	if err != nil {
+		return false
+	}
+
+	return len(raw) <= limit
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+	"visualizer-go/internal/lib/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLogMiddleware writes one structured record per request through the
+// request-scoped logger. Successful requests to quietRoutes, such as probes,
+// are logged at debug level. Only the route, tagged by RequestIDMiddleware, is
+// logged and never the raw path, which may carry share IDs and embed tokens.
+// It must run after RequestIDMiddleware and before gin.Recovery so requests
+// that panicked are logged as 500s.
+func AccessLogMiddleware(log *slog.Logger, quietRoutes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case slices.Contains(quietRoutes, c.FullPath()):
+			level = slog.LevelDebug
+		}
+
+		// The context is re-read because AuthMiddleware adds the user ID.
+		ctx := c.Request.Context()
+		logger.FromContext(ctx, log).LogAttrs(ctx, level, "request completed",
+			slog.String("method", c.Request.Method),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+		)
+	}
+}
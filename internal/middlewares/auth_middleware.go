@@ -1,31 +1,134 @@
 package middlewares
 
 import (
-	"fmt"
-	"github.com/gin-gonic/gin"
+	"context"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
 	"visualizer-go/internal/lib/response"
+	"visualizer-go/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-func AuthMiddleware(log *slog.Logger) gin.HandlerFunc {
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (auth.Identity, error)
+}
+
+func AuthMiddleware(log *slog.Logger, authenticator Authenticator) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		reqLog := logger.FromContext(ctx.Request.Context(), log)
+
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
-			log.Error("Authorization header is missing")
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
+			reqLog.Error("Authorization header is missing")
+			response.Error(ctx, http.StatusUnauthorized, "Authorization header is missing", nil)
 			ctx.Abort()
 			return
 		}
 
-		expectedToken := "test_token_123"
+		token, found := strings.CutPrefix(authHeader, "Bearer ")
+		if !found || token == "" {
+			reqLog.Error("Authorization header is malformed")
+			response.Error(ctx, http.StatusUnauthorized, "unauthorized", nil)
+			ctx.Abort()
+			return
+		}
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
+		identity, err := authenticator.Authenticate(ctx.Request.Context(), token)
+		if err != nil {
+			reqLog.Error("failed to authenticate request", logger.Err(err))
+			response.Error(ctx, http.StatusUnauthorized, "unauthorized", nil)
+			ctx.Abort()
+			return
+		}
 
-		if token != expectedToken {
-			log.Error(fmt.Sprintf("Invalid token: %s", token))
-			response.Error(ctx, http.StatusUnauthorized, "unauthorized", gin.H{"error": "unauthorized"})
+		reqCtx := auth.WithIdentity(ctx.Request.Context(), identity)
+		reqCtx = logger.WithContext(reqCtx, reqLog.With(slog.String("user_id", identity.UserID.String())))
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+
+		ctx.Next()
+	}
+}
+
+// RequireRole only lets through users whose role is one of roles. Admins are
+// held to their active organization's 2FA requirement like RequireOrgRole.
+// It must be registered after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		identity, ok := auth.FromContext(ctx.Request.Context())
+		if !ok || !slices.Contains(roles, identity.Role) {
+			response.Error(ctx, http.StatusForbidden, "forbidden", nil)
+			ctx.Abort()
+			return
+		}
+
+		if identity.Role == models.RoleAdmin && identity.MissingMFA() {
+			response.Error(ctx, http.StatusForbidden, "two-factor authentication is required for admins", nil)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireOrgRole only lets through users whose role within the active
+// organization is one of roles. Admins of organizations that enforce 2FA
+// are rejected unless their session passed a second factor.
+// It must be registered after AuthMiddleware.
+func RequireOrgRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		identity, ok := auth.FromContext(ctx.Request.Context())
+		if !ok || identity.OrgID == uuid.Nil {
+			response.Error(ctx, http.StatusForbidden, "no active organization", nil)
+			ctx.Abort()
+			return
+		}
+
+		if !slices.Contains(roles, identity.OrgRole) {
+			response.Error(ctx, http.StatusForbidden, "forbidden", nil)
+			ctx.Abort()
+			return
+		}
+
+		if identity.OrgRole == models.RoleAdmin && identity.MissingMFA() {
+			response.Error(ctx, http.StatusForbidden, "two-factor authentication is required for organization admins", nil)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireScope only lets through sessions and API keys granted scope.
+// It must be registered after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		identity, ok := auth.FromContext(ctx.Request.Context())
+		if !ok || !identity.HasScope(scope) {
+			response.Error(ctx, http.StatusForbidden, auth.ErrInsufficientScope.Error(), nil)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireSession rejects API keys, keeping account and organization
+// management limited to interactive sessions.
+// It must be registered after AuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		identity, ok := auth.FromContext(ctx.Request.Context())
+		if !ok || identity.IsAPIKey() {
+			response.Error(ctx, http.StatusForbidden, "API keys cannot access this resource", nil)
 			ctx.Abort()
 			return
 		}
@@ -0,0 +1,17 @@
+package middlewares
+
+import (
+	"visualizer-go/internal/lib/clientip"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIPMiddleware makes the caller's IP available to services through the
+// request context.
+func ClientIPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(clientip.WithContext(c.Request.Context(), c.ClientIP()))
+
+		c.Next()
+	}
+}
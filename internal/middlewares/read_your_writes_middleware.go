@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"time"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/db/replica"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// writeMarkerCookie carries the last write of the browser's user, so the
+// instance serving its next request knows to read from the primary.
+const writeMarkerCookie = "rw_marker"
+
+type WriteTracker interface {
+	MarkWrite(userID uuid.UUID) string
+	WithMarker(ctx context.Context, marker string) context.Context
+	StickyWindow() time.Duration
+}
+
+// ReadYourWritesMiddleware keeps reads consistent with a request's own
+// writes. Requests that may change data read from the primary, and once one
+// succeeds, the user's following requests do too for a while, on this
+// instance through the tracker and on others through a signed cookie. The
+// write is marked before the response goes out, so a client that reacts to
+// it at once already reads its change.
+func ReadYourWritesMiddleware(writes WriteTracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if marker, err := c.Cookie(writeMarkerCookie); err == nil {
+			c.Request = c.Request.WithContext(writes.WithMarker(c.Request.Context(), marker))
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		c.Request = c.Request.WithContext(replica.WithPrimary(c.Request.Context()))
+
+		w := &markingWriter{ResponseWriter: c.Writer, c: c, writes: writes}
+		c.Writer = w
+
+		c.Next()
+
+		// Responses without a body never pass through w on the way out.
+		if !w.Written() {
+			w.mark()
+		}
+	}
+}
+
+// markingWriter marks a successful write just before the response headers
+// are sent.
+type markingWriter struct {
+	gin.ResponseWriter
+	c      *gin.Context
+	writes WriteTracker
+	marked bool
+}
+
+func (w *markingWriter) mark() {
+	if w.marked {
+		return
+	}
+	w.marked = true
+
+	if w.Status() >= http.StatusBadRequest {
+		return
+	}
+	// The identity is only known once AuthMiddleware has run.
+	identity, ok := auth.FromContext(w.c.Request.Context())
+	if !ok {
+		return
+	}
+
+	marker := w.writes.MarkWrite(identity.UserID)
+	if marker == "" {
+		return
+	}
+
+	http.SetCookie(w.ResponseWriter, &http.Cookie{
+		Name:     writeMarkerCookie,
+		Value:    marker,
+		Path:     "/api",
+		MaxAge:   int(math.Ceil(w.writes.StickyWindow().Seconds())),
+		Secure:   w.c.Request.TLS != nil || w.c.GetHeader("X-Forwarded-Proto") == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (w *markingWriter) WriteHeaderNow() {
+	w.mark()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *markingWriter) Write(data []byte) (int, error) {
+	w.mark()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *markingWriter) WriteString(s string) (int, error) {
+	w.mark()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *markingWriter) Flush() {
+	w.mark()
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"log/slog"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/requestid"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDMiddleware assigns the request its ID, reusing the caller's
+// X-Request-ID when it is a valid ID, and stores a logger tagged with it in the
+// request context. It must run after TracingMiddleware so the logger can
+// also carry the trace ID.
+func RequestIDMiddleware(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		ctx := c.Request.Context()
+		reqLog := log.With(slog.String("request_id", id), slog.String("route", route))
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			reqLog = reqLog.With(slog.String("trace_id", sc.TraceID().String()))
+		}
+
+		ctx = requestid.WithContext(ctx, id)
+		ctx = logger.WithContext(ctx, reqLog)
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer.Header().Set(requestid.Header, id)
+
+		c.Next()
+	}
+}
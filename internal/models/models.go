@@ -5,19 +5,132 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// Publishing workflow states of a visualization.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusApproved  = "approved"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Reviewer decisions.
+const (
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Health probe and check states.
+const (
+	HealthStatusOK      = "ok"
+	HealthStatusFailing = "failing"
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	Username     string    `json:"username" db:"username"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	Role         string    `json:"role" db:"role"`
-	UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
-	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	ID             uuid.UUID `json:"id" db:"id"`
+	Username       string    `json:"username" db:"username"`
+	Email          *string   `json:"email" db:"email"`
+	PasswordHash   string    `json:"-" db:"password_hash"`
+	Role           string    `json:"role" db:"role"`
+	IsActive       bool      `json:"isActive" db:"is_active"`
+	SessionVersion int       `json:"-" db:"session_version"`
+	TOTPSecret     *string   `json:"-" db:"totp_secret"`
+	TOTPEnabled    bool      `json:"twoFactorEnabled" db:"totp_enabled"`
+	TOTPLastStep   *int64    `json:"-" db:"totp_last_step"`
+	OIDCIssuer     *string   `json:"-" db:"oidc_issuer"`
+	OIDCSubject    *string   `json:"-" db:"oidc_subject"`
+	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+}
+
+type Organization struct {
+	ID                    uuid.UUID `json:"id" db:"id"`
+	Name                  string    `json:"name" db:"name"`
+	Slug                  string    `json:"slug" db:"slug"`
+	Role                  *string   `json:"role,omitempty" db:"role"`
+	RequireAdminTwoFactor bool      `json:"requireAdminTwoFactor" db:"require_admin_two_factor"`
+	UpdatedAt             time.Time `json:"updatedAt" db:"updated_at"`
+	CreatedAt             time.Time `json:"createdAt" db:"created_at"`
+}
+
+type OrganizationMember struct {
+	OrgID                 uuid.UUID `json:"orgId" db:"org_id"`
+	UserID                uuid.UUID `json:"userId" db:"user_id"`
+	Username              string    `json:"username" db:"username"`
+	Role                  string    `json:"role" db:"role"`
+	RequireAdminTwoFactor bool      `json:"-" db:"require_admin_two_factor"`
+	CreatedAt             time.Time `json:"createdAt" db:"created_at"`
+}
+
+type Invitation struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	OrgID      uuid.UUID  `json:"orgId" db:"org_id"`
+	Email      string     `json:"email" db:"email"`
+	Role       string     `json:"role" db:"role"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	InvitedBy  *uuid.UUID `json:"invitedBy" db:"invited_by"`
+	ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+	AcceptedAt *time.Time `json:"acceptedAt" db:"accepted_at"`
+	RevokedAt  *time.Time `json:"revokedAt" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type APIKey struct {
+	ID         uuid.UUID      `json:"id" db:"id"`
+	UserID     uuid.UUID      `json:"userId" db:"user_id"`
+	OrgID      uuid.UUID      `json:"orgId" db:"org_id"`
+	Name       string         `json:"name" db:"name"`
+	Prefix     string         `json:"prefix" db:"prefix"`
+	KeyHash    string         `json:"-" db:"key_hash"`
+	Scopes     pq.StringArray `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time     `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *time.Time     `json:"lastUsedAt" db:"last_used_at"`
+	RevokedAt  *time.Time     `json:"revokedAt" db:"revoked_at"`
+	CreatedAt  time.Time      `json:"createdAt" db:"created_at"`
+}
+
+type ShareLink struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	VisualizationID uuid.UUID  `json:"visualizationId" db:"visualization_id"`
+	ShareID         uuid.UUID  `json:"shareId" db:"share_id"`
+	Name            string     `json:"name" db:"name"`
+	PasswordHash    *string    `json:"-" db:"password_hash"`
+	HasPassword     bool       `json:"hasPassword" db:"has_password"`
+	ExpiresAt       *time.Time `json:"expiresAt" db:"expires_at"`
+	RevokedAt       *time.Time `json:"revokedAt" db:"revoked_at"`
+	ViewCount       int        `json:"viewCount" db:"view_count"`
+	ViewedAt        *time.Time `json:"viewedAt" db:"viewed_at"`
+	CreatedBy       *uuid.UUID `json:"createdBy" db:"created_by"`
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
+}
+
+type AuditEntry struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	OrgID      *uuid.UUID      `json:"orgId" db:"org_id"`
+	ActorID    *uuid.UUID      `json:"actorId" db:"actor_id"`
+	ActorName  *string         `json:"actorName" db:"actor_name"`
+	Action     string          `json:"action" db:"action"`
+	EntityType string          `json:"entityType" db:"entity_type"`
+	EntityID   *string         `json:"entityId" db:"entity_id"`
+	Before     *types.JSONText `json:"before" db:"before"`
+	After      *types.JSONText `json:"after" db:"after"`
+	IP         *string         `json:"ip" db:"ip"`
+	RequestID  *string         `json:"requestId" db:"request_id"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
 }
 
 type Template struct {
 	ID          uuid.UUID       `json:"id" db:"id"`
+	OrgID       uuid.UUID       `json:"orgId" db:"org_id"`
 	Name        string          `json:"name" db:"name"`
 	Description *string         `json:"description" db:"description"`
 	Canvases    *types.JSONText `json:"canvases" db:"canvases"`
@@ -28,22 +141,118 @@ type Template struct {
 }
 
 type Visualization struct {
-	ID            uuid.UUID       `json:"id" db:"id"`
-	Name          string          `json:"name" db:"name"`
-	Description   *string         `json:"description" db:"description"`
-	Client        *string         `json:"client" db:"client"`
-	IsPublished   bool            `json:"published" db:"is_published"`
-	ShareID       uuid.UUID       `json:"shareId" db:"share_id"`
-	UpdatedAt     time.Time       `json:"updatedAt" db:"updated_at"`
-	CreatedAt     time.Time       `json:"createdAt" db:"created_at"`
-	UserID        uuid.UUID       `json:"userId" db:"user_id"`
-	TemplateID    *uuid.UUID      `json:"templateId" db:"template_id"`
-	TemplateName  *string         `json:"templateName" db:"template_name"`
-	Canvases      *types.JSONText `json:"canvases" db:"canvases"`
-	IsSaved       bool            `json:"saved" db:"is_saved"`
-	IsPublishable bool            `json:"publishable" db:"is_publishable"`
-	Tenant        *string         `json:"tenant" db:"tenant"`
-	Username      *string         `json:"username" db:"username"`
-	ViewCount     int             `json:"viewCount" db:"view_count"`
-	ViewedAt      *time.Time      `json:"viewedAt" db:"viewed_at"`
+	ID           uuid.UUID       `json:"id" db:"id"`
+	OrgID        uuid.UUID       `json:"orgId" db:"org_id"`
+	Name         string          `json:"name" db:"name"`
+	Description  *string         `json:"description" db:"description"`
+	Client       *string         `json:"client" db:"client"`
+	IsPublished  bool            `json:"published" db:"is_published"`
+	UpdatedAt    time.Time       `json:"updatedAt" db:"updated_at"`
+	CreatedAt    time.Time       `json:"createdAt" db:"created_at"`
+	UserID       uuid.UUID       `json:"userId" db:"user_id"`
+	TemplateID   *uuid.UUID      `json:"templateId" db:"template_id"`
+	TemplateName *string         `json:"templateName" db:"template_name"`
+	Canvases     *types.JSONText `json:"canvases" db:"canvases"`
+	Tenant       *string         `json:"tenant" db:"tenant"`
+	Username     *string         `json:"username" db:"username"`
+	ViewCount    int             `json:"viewCount" db:"view_count"`
+	ViewedAt     *time.Time      `json:"viewedAt" db:"viewed_at"`
+	Status       string          `json:"status" db:"status"`
+	Snapshot     *types.JSONText `json:"-" db:"published_snapshot"`
+	PublishedAt  *time.Time      `json:"publishedAt" db:"published_at"`
+	PublishedBy  *uuid.UUID      `json:"publishedBy" db:"published_by"`
+	PublishAt    *time.Time      `json:"publishAt" db:"publish_at"`
+	UnpublishAt  *time.Time      `json:"unpublishAt" db:"unpublish_at"`
+	ScheduledBy  *uuid.UUID      `json:"scheduledBy" db:"scheduled_by"`
+	// ShareID is deprecated: visualizations may have several share links.
+	// It holds the oldest active link, if any, for clients that predate
+	// /share-links, and is only filled by the organization-scoped reads.
+	ShareID *uuid.UUID `json:"shareId" db:"share_id"`
+}
+
+// PublicVisualization is what anonymous viewers of share links and embeds
+// receive: the snapshot frozen at publish time, without owners, organization
+// or view statistics.
+type PublicVisualization struct {
+	ID          uuid.UUID       `json:"id"`
+	Name        string          `json:"name"`
+	Description *string         `json:"description"`
+	Client      *string         `json:"client"`
+	TemplateID  *uuid.UUID      `json:"templateId"`
+	Canvases    *types.JSONText `json:"canvases"`
+	Tenant      *string         `json:"tenant"`
+	IsPublished bool            `json:"published"`
+	PublishedAt *time.Time      `json:"publishedAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+type VisualizationReview struct {
+	VisualizationID uuid.UUID  `json:"visualizationId" db:"visualization_id"`
+	ReviewerID      uuid.UUID  `json:"reviewerId" db:"reviewer_id"`
+	Username        string     `json:"username" db:"username"`
+	RequestedBy     *uuid.UUID `json:"requestedBy" db:"requested_by"`
+	Decision        *string    `json:"decision" db:"decision"`
+	Comment         *string    `json:"comment" db:"comment"`
+	RequestedAt     time.Time  `json:"requestedAt" db:"requested_at"`
+	DecidedAt       *time.Time `json:"decidedAt" db:"decided_at"`
+}
+
+// VisualizationView is a single deduplicated view of a visualization.
+type VisualizationView struct {
+	VisualizationID uuid.UUID  `db:"visualization_id"`
+	ShareLinkID     *uuid.UUID `db:"share_link_id"`
+	ViewerHash      string     `db:"viewer_hash"`
+	Referrer        *string    `db:"referrer"`
+	UserAgentClass  string     `db:"user_agent_class"`
+}
+
+type ViewBucket struct {
+	Start   time.Time `json:"start" db:"bucket"`
+	Views   int       `json:"views" db:"views"`
+	Viewers int       `json:"viewers" db:"viewers"`
+}
+
+type ReferrerCount struct {
+	Referrer string `json:"referrer" db:"referrer"`
+	Views    int    `json:"views" db:"views"`
+}
+
+type ViewAnalytics struct {
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Interval  string          `json:"interval"`
+	Views     int             `json:"views"`
+	Viewers   int             `json:"viewers"`
+	Series    []ViewBucket    `json:"series"`
+	Referrers []ReferrerCount `json:"referrers"`
+}
+
+// EntityCounts are totals across all organizations, used for monitoring.
+type EntityCounts struct {
+	Visualizations          int `db:"visualizations"`
+	PublishedVisualizations int `db:"published_visualizations"`
+	Templates               int `db:"templates"`
+	Users                   int `db:"users"`
+}
+
+// ScheduledTransition is a publish or unpublish applied by the scheduler.
+type ScheduledTransition struct {
+	VisualizationID uuid.UUID  `db:"id"`
+	OrgID           uuid.UUID  `db:"org_id"`
+	Status          string     `db:"status"`
+	ScheduledBy     *uuid.UUID `db:"scheduled_by"`
+	DueAt           time.Time  `db:"due_at"`
+}
+
+// HealthCheck is the outcome of a single readiness check.
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport is the body of the liveness and readiness probes.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
 }
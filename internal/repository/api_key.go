@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrAPIKeyNotFound       = errors.New("API key not found")
+	ErrFailedToCreateAPIKey = errors.New("failed to create API key")
+	ErrFailedToFetchAPIKeys = errors.New("failed to fetch API keys")
+	ErrFailedToRevokeAPIKey = errors.New("failed to revoke API key")
+)
+
+type APIKeyRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewAPIKeyRepo(log *slog.Logger, db *sqlx.DB) *APIKeyRepo {
+	return &APIKeyRepo{log: log, db: db}
+}
+
+func (r *APIKeyRepo) Create(ctx context.Context, userID, orgID uuid.UUID, dto dto.APIKeyCreateDto, prefix, keyHash string) (models.APIKey, error) {
+	const op = "repository.APIKeyRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var key models.APIKey
+
+	query := `
+  INSERT INTO api_keys (user_id, org_id, name, prefix, key_hash, scopes, expires_at)
+  VALUES ($1, $2, $3, $4, $5, $6, $7)
+  RETURNING *
+  `
+
+	err := r.db.GetContext(ctx, &key, query,
+		userID, orgID, dto.Name, prefix, keyHash, pq.StringArray(dto.Scopes), dto.ExpiresAt)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return key, fmt.Errorf("%s: %w", op, ErrFailedToCreateAPIKey)
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepo) GetAllForUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	const op = "repository.APIKeyRepo.GetAllForUser"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	keys := make([]models.APIKey, 0)
+
+	if err := r.db.SelectContext(ctx, &keys, "SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC", userID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrFailedToFetchAPIKeys)
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepo) GetByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	const op = "repository.APIKeyRepo.GetByHash"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var key models.APIKey
+
+	if err := r.db.GetContext(ctx, &key, "SELECT * FROM api_keys WHERE key_hash = $1", keyHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return key, fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+		}
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return key, fmt.Errorf("%s: %w", op, ErrFailedToFetchAPIKeys)
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepo) Revoke(ctx context.Context, userID, keyID uuid.UUID) error {
+	const op = "repository.APIKeyRepo.Revoke"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", keyID, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToRevokeAPIKey)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound))
+}
+
+// Touch records that the key was used. Writes are throttled to one per minute
+// so busy automation does not turn every request into an UPDATE.
+func (r *APIKeyRepo) Touch(ctx context.Context, keyID uuid.UUID) error {
+	const op = "repository.APIKeyRepo.Touch"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx, `
+  UPDATE api_keys SET last_used_at = NOW()
+  WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+  `, keyID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
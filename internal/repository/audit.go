@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrFailedToWriteAudit = errors.New("failed to write audit entry")
+	ErrFailedToFetchAudit = errors.New("failed to fetch audit log")
+)
+
+// maxAuditExportRows caps CSV exports so a missing filter cannot stream the
+// whole table.
+const maxAuditExportRows = 100000
+
+type AuditRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewAuditRepo(log *slog.Logger, db *sqlx.DB) *AuditRepo {
+	return &AuditRepo{log: log, db: db}
+}
+
+func (r *AuditRepo) Create(ctx context.Context, entry models.AuditEntry) error {
+	const op = "repository.AuditRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `
+  INSERT INTO audit_log (org_id, actor_id, actor_name, action, entity_type, entity_id, before, after, ip, request_id)
+  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+  `
+
+	_, err := r.db.ExecContext(ctx, query,
+		entry.OrgID, entry.ActorID, entry.ActorName, entry.Action, entry.EntityType, entry.EntityID,
+		entry.Before, entry.After, entry.IP, entry.RequestID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToWriteAudit)
+	}
+
+	return nil
+}
+
+func (r *AuditRepo) GetAll(ctx context.Context, query dto.AuditQuery) ([]models.AuditEntry, int, error) {
+	const op = "repository.AuditRepo.GetAll"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	entries := make([]models.AuditEntry, 0)
+	where, args := auditFilter(query)
+
+	q := fmt.Sprintf("SELECT * FROM audit_log %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d",
+		where, len(args)+1, len(args)+2)
+
+	if err := r.db.SelectContext(ctx, &entries, q, append(args, query.Limit, (query.Page-1)*query.Limit)...); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, 0, fmt.Errorf("%s: %w", op, ErrFailedToFetchAudit)
+	}
+
+	var rowCount int
+	if err := r.db.GetContext(ctx, &rowCount, "SELECT COUNT(*) FROM audit_log "+where, args...); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, 0, fmt.Errorf("%s: %w", op, ErrFailedToFetchAudit)
+	}
+
+	return entries, rowCount, nil
+}
+
+// Export streams every entry matching query, oldest first, to fn.
+func (r *AuditRepo) Export(ctx context.Context, query dto.AuditQuery, fn func(models.AuditEntry) error) error {
+	const op = "repository.AuditRepo.Export"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	where, args := auditFilter(query)
+	q := fmt.Sprintf("SELECT * FROM audit_log %s ORDER BY created_at LIMIT %d", where, maxAuditExportRows)
+
+	rows, err := r.db.QueryxContext(ctx, q, args...)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToFetchAudit)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err = rows.StructScan(&entry); err != nil {
+			logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return fmt.Errorf("%s: %w", op, ErrFailedToFetchAudit)
+		}
+		if err = fn(entry); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToFetchAudit)
+	}
+
+	return nil
+}
+
+func auditFilter(query dto.AuditQuery) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if query.OrgID != "" {
+		add("org_id = $%d", query.OrgID)
+	}
+	if query.ActorID != "" {
+		add("actor_id = $%d", query.ActorID)
+	}
+	if query.Action != "" {
+		add("action = $%d", query.Action)
+	}
+	if query.EntityType != "" {
+		add("entity_type = $%d", query.EntityType)
+	}
+	if query.EntityID != "" {
+		add("entity_id = $%d", query.EntityID)
+	}
+	if query.From != nil {
+		add("created_at >= $%d", *query.From)
+	}
+	if query.To != nil {
+		add("created_at < $%d", *query.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrDatabaseUnavailable        = errors.New("database unavailable")
+	ErrFailedToFetchSchemaVersion = errors.New("failed to fetch schema version")
+)
+
+type HealthRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewHealthRepo(log *slog.Logger, db *sqlx.DB) *HealthRepo {
+	return &HealthRepo{log: log, db: db}
+}
+
+func (r *HealthRepo) Ping(ctx context.Context) error {
+	const op = "repository.HealthRepo.Ping"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if err := r.db.PingContext(ctx); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrDatabaseUnavailable)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the migration version golang-migrate recorded and
+// whether that migration failed halfway.
+func (r *HealthRepo) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	const op = "repository.HealthRepo.SchemaVersion"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var (
+		version uint
+		dirty   bool
+	)
+
+	query := `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	if err := r.db.QueryRowxContext(ctx, query).Scan(&version, &dirty); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return 0, false, fmt.Errorf("%s: %w", op, ErrFailedToFetchSchemaVersion)
+	}
+
+	return version, dirty, nil
+}
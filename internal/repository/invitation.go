@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrInvitationNotFound         = errors.New("invitation not found")
+	ErrInvitationInvalid          = errors.New("invitation is invalid or has expired")
+	ErrFailedToCreateInvitation   = errors.New("failed to create invitation")
+	ErrFailedToFetchInvitations   = errors.New("failed to fetch invitations")
+	ErrFailedToRevokeInvitation   = errors.New("failed to revoke invitation")
+	ErrFailedToAcceptInvitation   = errors.New("failed to accept invitation")
+	ErrUsernameOrEmailAlreadyUsed = errors.New("username or email is already in use")
+	ErrInvitationAccountRequired  = errors.New("username and password are required to create an account")
+)
+
+type InvitationRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewInvitationRepo(log *slog.Logger, db *sqlx.DB) *InvitationRepo {
+	return &InvitationRepo{log: log, db: db}
+}
+
+func (r *InvitationRepo) Create(ctx context.Context, dto dto.InvitationCreateDto, tokenHash string, invitedBy uuid.UUID, expiresAt time.Time) (uuid.UUID, error) {
+	const op = "repository.InvitationRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var invitationID uuid.UUID
+	err = r.db.GetContext(ctx, &invitationID,
+		"INSERT INTO invitations (org_id, email, role, token_hash, invited_by, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		orgID, dto.Email, dto.Role, tokenHash, invitedBy, expiresAt)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateInvitation)
+	}
+
+	return invitationID, nil
+}
+
+func (r *InvitationRepo) GetAll(ctx context.Context) ([]models.Invitation, error) {
+	const op = "repository.InvitationRepo.GetAll"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	invitations := make([]models.Invitation, 0)
+	if err = r.db.SelectContext(ctx, &invitations, "SELECT * FROM invitations WHERE org_id = $1 ORDER BY created_at DESC", orgID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrFailedToFetchInvitations)
+	}
+
+	return invitations, nil
+}
+
+func (r *InvitationRepo) Revoke(ctx context.Context, invitationID uuid.UUID) error {
+	const op = "repository.InvitationRepo.Revoke"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE invitations SET revoked_at = NOW() WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL",
+		invitationID, orgID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToRevokeInvitation)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrInvitationNotFound))
+}
+
+// Delete removes an invitation whose email could not be sent, so no unusable
+// invitation is left behind.
+func (r *InvitationRepo) Delete(ctx context.Context, invitationID uuid.UUID) error {
+	const op = "repository.InvitationRepo.Delete"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM invitations WHERE id = $1 AND org_id = $2", invitationID, orgID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToRevokeInvitation)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrInvitationNotFound))
+}
+
+// GetByTokenHash looks an invitation up by the hash of its token. It is not
+// scoped to an organization because the invitee is not authenticated yet.
+func (r *InvitationRepo) GetByTokenHash(ctx context.Context, tokenHash string) (models.Invitation, error) {
+	const op = "repository.InvitationRepo.GetByTokenHash"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var invitation models.Invitation
+	if err := r.db.GetContext(ctx, &invitation, "SELECT * FROM invitations WHERE token_hash = $1", tokenHash); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return invitation, fmt.Errorf("%s: %w", op, ErrInvitationNotFound)
+		}
+		return invitation, fmt.Errorf("%s: %w", op, ErrFailedToFetchInvitations)
+	}
+
+	return invitation, nil
+}
+
+// Accept adds the invitee to the organization and marks the invitation as
+// used, all in one transaction. An account with the invited email joins as
+// is; otherwise one is created from username and passwordHash, and created
+// reports so. The invitation row is locked so that a token can only ever be
+// redeemed once.
+func (r *InvitationRepo) Accept(ctx context.Context, invitationID uuid.UUID, username, passwordHash string) (userID uuid.UUID, created bool, err error) {
+	const op = "repository.InvitationRepo.Accept"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrFailedToAcceptInvitation)
+	}
+	defer tx.Rollback()
+
+	var invitation models.Invitation
+	err = tx.GetContext(ctx, &invitation, `
+  SELECT * FROM invitations
+  WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+  FOR UPDATE
+  `, invitationID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrInvitationInvalid)
+		}
+		return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrFailedToAcceptInvitation)
+	}
+
+	err = tx.GetContext(ctx, &userID, "SELECT id FROM users WHERE LOWER(email) = LOWER($1)", invitation.Email)
+	switch {
+	case err == nil:
+	case errors.Is(err, sql.ErrNoRows):
+		if username == "" || passwordHash == "" {
+			return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrInvitationAccountRequired)
+		}
+		err = tx.GetContext(ctx, &userID,
+			"INSERT INTO users (username, email, password_hash, role) VALUES ($1, $2, $3, $4) RETURNING id",
+			username, invitation.Email, passwordHash, models.RoleViewer)
+		if err != nil {
+			logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			if isPgError(err, errUniqueViolation) {
+				return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrUsernameOrEmailAlreadyUsed)
+			}
+			return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrFailedToAcceptInvitation)
+		}
+		created = true
+	default:
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrFailedToAcceptInvitation)
+	}
+
+	// Someone who already belongs to the organization keeps their role.
+	_, err = tx.ExecContext(ctx, `
+  INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)
+  ON CONFLICT (org_id, user_id) DO NOTHING
+  `, invitation.OrgID, userID, invitation.Role)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrFailedToAcceptInvitation)
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE invitations SET accepted_at = NOW() WHERE id = $1", invitationID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrFailedToAcceptInvitation)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, false, fmt.Errorf("%s: %w", op, ErrFailedToAcceptInvitation)
+	}
+
+	return userID, created, nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/lib/bruteforce"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrFailedToTrackLoginAttempt = errors.New("failed to track login attempt")
+
+// LoginAttemptRepo is a bruteforce.Store shared by all application instances.
+type LoginAttemptRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+var _ bruteforce.Store = (*LoginAttemptRepo)(nil)
+
+func NewLoginAttemptRepo(log *slog.Logger, db *sqlx.DB) *LoginAttemptRepo {
+	return &LoginAttemptRepo{log: log, db: db}
+}
+
+type loginAttemptRow struct {
+	Failures      int          `db:"failures"`
+	LastFailureAt time.Time    `db:"last_failure_at"`
+	LockedUntil   sql.NullTime `db:"locked_until"`
+}
+
+func (row loginAttemptRow) toAttempt() bruteforce.Attempt {
+	return bruteforce.Attempt{
+		Failures:      row.Failures,
+		LastFailureAt: row.LastFailureAt,
+		LockedUntil:   row.LockedUntil.Time,
+	}
+}
+
+func (r *LoginAttemptRepo) Get(ctx context.Context, key string) (bruteforce.Attempt, error) {
+	const op = "repository.LoginAttemptRepo.Get"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var row loginAttemptRow
+	err := r.db.GetContext(ctx, &row, "SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1", key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return bruteforce.Attempt{}, nil
+		}
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return bruteforce.Attempt{}, fmt.Errorf("%s: %w", op, ErrFailedToTrackLoginAttempt)
+	}
+
+	return row.toAttempt(), nil
+}
+
+// Fail increments the counter with a single upsert so concurrent instances
+// never lose a failure, then stores the resulting lock.
+func (r *LoginAttemptRepo) Fail(ctx context.Context, key string, now time.Time, resetAfter time.Duration, lockFor bruteforce.LockFunc) (bruteforce.Attempt, error) {
+	const op = "repository.LoginAttemptRepo.Fail"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var row loginAttemptRow
+	err := r.db.GetContext(ctx, &row, `
+  INSERT INTO login_attempts (key, failures, last_failure_at)
+  VALUES ($1, 1, $2)
+  ON CONFLICT (key) DO UPDATE SET
+    failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+    last_failure_at = EXCLUDED.last_failure_at
+  RETURNING failures, last_failure_at, locked_until
+  `, key, now, now.Add(-resetAfter))
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return bruteforce.Attempt{}, fmt.Errorf("%s: %w", op, ErrFailedToTrackLoginAttempt)
+	}
+
+	attempt := row.toAttempt()
+
+	if lock := lockFor(attempt.Failures); lock > 0 {
+		attempt.LockedUntil = now.Add(lock)
+		_, err = r.db.ExecContext(ctx,
+			"UPDATE login_attempts SET locked_until = GREATEST(COALESCE(locked_until, $2), $2) WHERE key = $1",
+			key, attempt.LockedUntil)
+		if err != nil {
+			logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return bruteforce.Attempt{}, fmt.Errorf("%s: %w", op, ErrFailedToTrackLoginAttempt)
+		}
+	}
+
+	return attempt, nil
+}
+
+func (r *LoginAttemptRepo) Reset(ctx context.Context, key string) error {
+	const op = "repository.LoginAttemptRepo.Reset"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToTrackLoginAttempt)
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrOrganizationNotFound       = errors.New("organization not found")
+	ErrOrganizationSlugTaken      = errors.New("organization slug is already taken")
+	ErrFailedToFetchOrganizations = errors.New("failed to fetch organizations")
+	ErrFailedToCreateOrganization = errors.New("failed to create organization")
+	ErrFailedToUpdateOrganization = errors.New("failed to update organization")
+	ErrMemberNotFound             = errors.New("organization member not found")
+	ErrMemberAlreadyExists        = errors.New("user is already a member of the organization")
+	ErrFailedToFetchMembers       = errors.New("failed to fetch organization members")
+	ErrFailedToUpdateMember       = errors.New("failed to update organization member")
+	ErrNoActiveOrganization       = errors.New("no active organization")
+)
+
+const (
+	errUniqueViolation     pq.ErrorCode = "23505"
+	errForeignKeyViolation pq.ErrorCode = "23503"
+)
+
+type OrganizationRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewOrganizationRepo(log *slog.Logger, db *sqlx.DB) *OrganizationRepo {
+	return &OrganizationRepo{log: log, db: db}
+}
+
+func (r *OrganizationRepo) GetAllForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	const op = "repository.OrganizationRepo.GetAllForUser"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	organizations := make([]models.Organization, 0)
+
+	query := `
+  SELECT
+    o.id,
+    o.name,
+    o.slug,
+    m.role,
+    o.require_admin_two_factor,
+    o.updated_at,
+    o.created_at
+  FROM organizations o
+  JOIN organization_members m ON m.org_id = o.id
+  WHERE m.user_id = $1
+  ORDER BY m.created_at, o.name
+  `
+
+	if err := r.db.SelectContext(ctx, &organizations, query, userID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrFailedToFetchOrganizations)
+	}
+
+	return organizations, nil
+}
+
+func (r *OrganizationRepo) Create(ctx context.Context, dto dto.OrganizationCreateDto, ownerID uuid.UUID) (uuid.UUID, error) {
+	const op = "repository.OrganizationRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateOrganization)
+	}
+	defer tx.Rollback()
+
+	var orgID uuid.UUID
+	err = tx.GetContext(ctx, &orgID, "INSERT INTO organizations (name, slug) VALUES ($1, $2) RETURNING id", dto.Name, dto.Slug)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if isPgError(err, errUniqueViolation) {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrOrganizationSlugTaken)
+		}
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateOrganization)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)",
+		orgID, ownerID, models.RoleAdmin)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateOrganization)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateOrganization)
+	}
+
+	return orgID, nil
+}
+
+func (r *OrganizationRepo) GetMembership(ctx context.Context, orgID, userID uuid.UUID) (models.OrganizationMember, error) {
+	const op = "repository.OrganizationRepo.GetMembership"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var member models.OrganizationMember
+
+	query := `
+  SELECT m.org_id, m.user_id, u.username, m.role, o.require_admin_two_factor, m.created_at
+  FROM organization_members m
+  JOIN users u ON u.id = m.user_id
+  JOIN organizations o ON o.id = m.org_id
+  WHERE m.org_id = $1 AND m.user_id = $2
+  `
+
+	if err := r.db.GetContext(ctx, &member, query, orgID, userID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return member, fmt.Errorf("%s: %w", op, ErrMemberNotFound)
+		}
+		return member, fmt.Errorf("%s: %w", op, ErrFailedToFetchMembers)
+	}
+
+	return member, nil
+}
+
+func (r *OrganizationRepo) GetMembers(ctx context.Context, orgID uuid.UUID) ([]models.OrganizationMember, error) {
+	const op = "repository.OrganizationRepo.GetMembers"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	members := make([]models.OrganizationMember, 0)
+
+	query := `
+  SELECT m.org_id, m.user_id, u.username, m.role, m.created_at
+  FROM organization_members m
+  JOIN users u ON u.id = m.user_id
+  WHERE m.org_id = $1
+  ORDER BY u.username
+  `
+
+	if err := r.db.SelectContext(ctx, &members, query, orgID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrFailedToFetchMembers)
+	}
+
+	return members, nil
+}
+
+func (r *OrganizationRepo) AddMember(ctx context.Context, orgID uuid.UUID, dto dto.OrganizationMemberCreateDto) error {
+	const op = "repository.OrganizationRepo.AddMember"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx, "INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)",
+		orgID, dto.UserID, dto.Role)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		switch {
+		case isPgError(err, errUniqueViolation):
+			return fmt.Errorf("%s: %w", op, ErrMemberAlreadyExists)
+		case isPgError(err, errForeignKeyViolation):
+			return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateMember)
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepo) UpdateMember(ctx context.Context, orgID, userID uuid.UUID, dto dto.OrganizationMemberUpdateDto) error {
+	const op = "repository.OrganizationRepo.UpdateMember"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	res, err := r.db.ExecContext(ctx, "UPDATE organization_members SET role=$1 WHERE org_id=$2 AND user_id=$3",
+		dto.Role, orgID, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateMember)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrMemberNotFound))
+}
+
+func (r *OrganizationRepo) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	const op = "repository.OrganizationRepo.RemoveMember"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM organization_members WHERE org_id=$1 AND user_id=$2", orgID, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateMember)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrMemberNotFound))
+}
+
+func (r *OrganizationRepo) Update(ctx context.Context, orgID uuid.UUID, dto dto.OrganizationUpdateDto) error {
+	const op = "repository.OrganizationRepo.Update"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE organizations SET require_admin_two_factor=COALESCE($1, require_admin_two_factor), updated_at=NOW() WHERE id=$2",
+		dto.RequireAdminTwoFactor, orgID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateOrganization)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrOrganizationNotFound))
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrResetTokenInvalid        = errors.New("reset token is invalid or has expired")
+	ErrFailedToCreateResetToken = errors.New("failed to create password reset token")
+	ErrFailedToResetPassword    = errors.New("failed to reset password")
+)
+
+type PasswordResetRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewPasswordResetRepo(log *slog.Logger, db *sqlx.DB) *PasswordResetRepo {
+	return &PasswordResetRepo{log: log, db: db}
+}
+
+func (r *PasswordResetRepo) Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time, ip string) error {
+	const op = "repository.PasswordResetRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, requested_ip) VALUES ($1, $2, $3, $4)",
+		userID, tokenHash, expiresAt, ip)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToCreateResetToken)
+	}
+
+	return nil
+}
+
+// Reset consumes the token, stores the new password hash and revokes every
+// session and API key of the user. Any other outstanding reset tokens are
+// invalidated too.
+func (r *PasswordResetRepo) Reset(ctx context.Context, tokenHash string, passwordHash string) (uuid.UUID, error) {
+	const op = "repository.PasswordResetRepo.Reset"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToResetPassword)
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.GetContext(ctx, &userID, `
+  SELECT user_id FROM password_reset_tokens
+  WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+  FOR UPDATE
+  `, tokenHash)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrResetTokenInvalid)
+		}
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToResetPassword)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE users SET password_hash = $1, session_version = session_version + 1, updated_at = NOW() WHERE id = $2",
+		passwordHash, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToResetPassword)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToResetPassword)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToResetPassword)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToResetPassword)
+	}
+
+	return userID, nil
+}
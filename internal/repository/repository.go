@@ -2,12 +2,19 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log/slog"
+	"strings"
+	"time"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/db/replica"
 	"visualizer-go/internal/models"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type (
@@ -19,34 +26,201 @@ type (
 	}
 
 	User interface {
+		GetAll(ctx context.Context, search string, limit, offset int) ([]models.User, int, error)
 		GetByID(ctx context.Context, userID uuid.UUID) (models.User, error)
 		GetByUsername(ctx context.Context, username string) (models.User, error)
-		Create(ctx context.Context, dto dto.UserCreateDto) error
+		GetByEmail(ctx context.Context, email string) (models.User, error)
+		GetByOIDCSubject(ctx context.Context, issuer, subject string) (models.User, error)
+		Create(ctx context.Context, dto dto.UserCreateDto) (uuid.UUID, error)
+		CreateOIDC(ctx context.Context, dto dto.OIDCUserDto) (models.User, error)
+		LinkOIDC(ctx context.Context, userID uuid.UUID, issuer, subject string) error
 		Update(ctx context.Context, userID uuid.UUID, dto dto.UserUpdateDto) error
+		SetActive(ctx context.Context, userID uuid.UUID, active bool) error
+		Delete(ctx context.Context, userID uuid.UUID, reassignTo uuid.UUID) error
 	}
 
 	Visualization interface {
 		GetAll(ctx context.Context) ([]models.Visualization, error)
 		GetByTemplateID(ctx context.Context, templateID uuid.UUID) ([]models.Visualization, error)
 		GetByID(ctx context.Context, visualizationID uuid.UUID) (models.Visualization, error)
-		GetByShareID(ctx context.Context, shareID uuid.UUID) (models.Visualization, error)
+		GetPublishedByID(ctx context.Context, visualizationID uuid.UUID) (models.Visualization, error)
 		Create(ctx context.Context, dto dto.VisualizationCreateDto) (uuid.UUID, error)
 		Update(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationUpdateDto) error
-    IncrementViewCount(ctx context.Context, visualizationID uuid.UUID) error
+		Publish(ctx context.Context, visualizationID, publishedBy uuid.UUID) error
+		Transition(ctx context.Context, visualizationID uuid.UUID, from []string, to string) error
+		Schedule(ctx context.Context, visualizationID uuid.UUID, publishAt, unpublishAt *time.Time, scheduledBy uuid.UUID) error
+		PublishDue(ctx context.Context, limit int) ([]models.ScheduledTransition, error)
+		UnpublishDue(ctx context.Context, limit int) ([]models.ScheduledTransition, error)
+		AddViewCounts(ctx context.Context, counts map[uuid.UUID]int) error
 		Delete(ctx context.Context, visualizationID uuid.UUID) error
 	}
 
+	Review interface {
+		Submit(ctx context.Context, visualizationID, requestedBy uuid.UUID, reviewers []uuid.UUID) error
+		Decide(ctx context.Context, visualizationID, reviewerID uuid.UUID, decision, comment string) (string, error)
+		GetAll(ctx context.Context, visualizationID uuid.UUID) ([]models.VisualizationReview, error)
+	}
+
+	View interface {
+		Record(ctx context.Context, view models.VisualizationView, window time.Duration) (bool, error)
+		GetAnalytics(ctx context.Context, visualizationID uuid.UUID, from, to time.Time, interval string) (models.ViewAnalytics, error)
+	}
+
+	Organization interface {
+		GetAllForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error)
+		Create(ctx context.Context, dto dto.OrganizationCreateDto, ownerID uuid.UUID) (uuid.UUID, error)
+		GetMembership(ctx context.Context, orgID, userID uuid.UUID) (models.OrganizationMember, error)
+		GetMembers(ctx context.Context, orgID uuid.UUID) ([]models.OrganizationMember, error)
+		AddMember(ctx context.Context, orgID uuid.UUID, dto dto.OrganizationMemberCreateDto) error
+		UpdateMember(ctx context.Context, orgID, userID uuid.UUID, dto dto.OrganizationMemberUpdateDto) error
+		RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+		Update(ctx context.Context, orgID uuid.UUID, dto dto.OrganizationUpdateDto) error
+	}
+
+	Invitation interface {
+		Create(ctx context.Context, dto dto.InvitationCreateDto, tokenHash string, invitedBy uuid.UUID, expiresAt time.Time) (uuid.UUID, error)
+		GetAll(ctx context.Context) ([]models.Invitation, error)
+		Revoke(ctx context.Context, invitationID uuid.UUID) error
+		Delete(ctx context.Context, invitationID uuid.UUID) error
+		GetByTokenHash(ctx context.Context, tokenHash string) (models.Invitation, error)
+		Accept(ctx context.Context, invitationID uuid.UUID, username, passwordHash string) (uuid.UUID, bool, error)
+	}
+
+	PasswordReset interface {
+		Create(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time, ip string) error
+		Reset(ctx context.Context, tokenHash string, passwordHash string) (uuid.UUID, error)
+	}
+
+	TwoFactor interface {
+		Get(ctx context.Context, userID uuid.UUID) (models.User, error)
+		SetSecret(ctx context.Context, userID uuid.UUID, secret string) error
+		Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+		Disable(ctx context.Context, userID uuid.UUID) error
+		UseStep(ctx context.Context, userID uuid.UUID, step int64) error
+		UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+	}
+
+	APIKey interface {
+		Create(ctx context.Context, userID, orgID uuid.UUID, dto dto.APIKeyCreateDto, prefix, keyHash string) (models.APIKey, error)
+		GetAllForUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+		GetByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+		Revoke(ctx context.Context, userID, keyID uuid.UUID) error
+		Touch(ctx context.Context, keyID uuid.UUID) error
+	}
+
+	ShareLink interface {
+		Create(ctx context.Context, visualizationID uuid.UUID, dto dto.ShareLinkCreateDto, passwordHash *string, createdBy uuid.UUID) (models.ShareLink, error)
+		GetAllForVisualization(ctx context.Context, visualizationID uuid.UUID) ([]models.ShareLink, error)
+		GetByShareID(ctx context.Context, shareID uuid.UUID) (models.ShareLink, error)
+		Rotate(ctx context.Context, visualizationID, linkID uuid.UUID) (uuid.UUID, error)
+		Revoke(ctx context.Context, visualizationID, linkID uuid.UUID) error
+		AddViewCounts(ctx context.Context, counts map[uuid.UUID]int) error
+	}
+
+	Health interface {
+		Ping(ctx context.Context) error
+		SchemaVersion(ctx context.Context) (uint, bool, error)
+	}
+
+	Stats interface {
+		CountEntities(ctx context.Context) (models.EntityCounts, error)
+	}
+
+	Audit interface {
+		Create(ctx context.Context, entry models.AuditEntry) error
+		GetAll(ctx context.Context, query dto.AuditQuery) ([]models.AuditEntry, int, error)
+		Export(ctx context.Context, query dto.AuditQuery, fn func(models.AuditEntry) error) error
+	}
+
 	Repository struct {
+		APIKey
+		Audit
+		Health
+		Invitation
+		Organization
+		PasswordReset
+		Review
+		ShareLink
+		Stats
 		Template
+		TwoFactor
 		User
+		View
 		Visualization
+
+		// Reads routes the read-heavy queries to the replica. Callers
+		// report writes to it for read-your-writes consistency.
+		Reads *replica.Router
 	}
 )
 
-func New(log *slog.Logger, db *sqlx.DB) *Repository {
+// New returns the repositories over the primary db. When replicaDB is not
+// nil, the read-heavy queries go to it, except for users who wrote within
+// stickyWindow.
+func New(log *slog.Logger, db, replicaDB *sqlx.DB, stickyWindow time.Duration, markerKey string) *Repository {
+	reads := replica.NewRouter(log, db, replicaDB, stickyWindow, markerKey)
+
 	return &Repository{
-		Template:      NewTemplateRepo(log, db),
+		APIKey:        NewAPIKeyRepo(log, db),
+		Audit:         NewAuditRepo(log, db),
+		Health:        NewHealthRepo(log, db),
+		Invitation:    NewInvitationRepo(log, db),
+		Organization:  NewOrganizationRepo(log, db),
+		PasswordReset: NewPasswordResetRepo(log, db),
+		Review:        NewReviewRepo(log, db),
+		ShareLink:     NewShareLinkRepo(log, db, reads),
+		Stats:         NewStatsRepo(log, db),
+		Template:      NewTemplateRepo(log, db, reads),
+		TwoFactor:     NewTwoFactorRepo(log, db),
 		User:          NewUserRepo(log, db),
-		Visualization: NewVisualizationRepo(log, db),
+		View:          NewViewRepo(log, db),
+		Visualization: NewVisualizationRepo(log, db, reads),
+		Reads:         reads,
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// checkAffected returns notFound when the statement did not touch any row.
+func checkAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// viewCountArrays turns buffered counts into parallel arrays for unnest.
+func viewCountArrays(counts map[uuid.UUID]int) (pq.StringArray, pq.Int64Array) {
+	ids := make(pq.StringArray, 0, len(counts))
+	ns := make(pq.Int64Array, 0, len(counts))
+	for id, n := range counts {
+		ids = append(ids, id.String())
+		ns = append(ns, int64(n))
+	}
+	return ids, ns
+}
+
+// isPgError reports whether err is a Postgres error with the given code.
+func isPgError(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
+// activeOrgID returns the organization every tenant-scoped query must be
+// filtered by. It is taken from the authenticated identity, never from input.
+func activeOrgID(ctx context.Context) (uuid.UUID, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.OrgID == uuid.Nil {
+		return uuid.Nil, ErrNoActiveOrganization
 	}
+	return identity.OrgID, nil
 }
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInvalidReviewers      = errors.New("reviewers must be editors or admins of the organization")
+	ErrNotPendingReviewer    = errors.New("you are not a pending reviewer of this visualization")
+	ErrFailedToFetchReviews  = errors.New("failed to fetch reviews")
+	ErrFailedToUpdateReviews = errors.New("failed to update reviews")
+)
+
+type ReviewRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewReviewRepo(log *slog.Logger, db *sqlx.DB) *ReviewRepo {
+	return &ReviewRepo{log: log, db: db}
+}
+
+// Submit moves a draft into review and replaces its reviewers. Every reviewer
+// has to be an editor or admin of the active organization.
+func (r *ReviewRepo) Submit(ctx context.Context, visualizationID, requestedBy uuid.UUID, reviewers []uuid.UUID) error {
+	const op = "repository.ReviewRepo.Submit"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.GetContext(ctx, &status, "SELECT status FROM visualizations WHERE id = $1 AND org_id = $2 FOR UPDATE", visualizationID, orgID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, ErrVisualizationNotFound)
+		}
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+	if status != models.StatusDraft {
+		return fmt.Errorf("%s: %w", op, ErrInvalidTransition)
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM visualization_reviews WHERE visualization_id = $1", visualizationID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+
+	ids := make(pq.StringArray, 0, len(reviewers))
+	for _, reviewer := range reviewers {
+		ids = append(ids, reviewer.String())
+	}
+
+	res, err := tx.ExecContext(ctx, `
+  INSERT INTO visualization_reviews (visualization_id, reviewer_id, requested_by)
+  SELECT $1, user_id, $3
+  FROM organization_members
+  WHERE org_id = $2 AND user_id = ANY($4::uuid[]) AND role IN ('admin', 'editor')
+  `, visualizationID, orgID, requestedBy, ids)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if int(n) != len(reviewers) {
+		return fmt.Errorf("%s: %w", op, ErrInvalidReviewers)
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE visualizations SET status = 'in_review' WHERE id = $1", visualizationID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+
+	return nil
+}
+
+// Decide records a reviewer's decision and returns the resulting state: a
+// rejection sends the visualization back to draft, the last approval moves it
+// to approved.
+func (r *ReviewRepo) Decide(ctx context.Context, visualizationID, reviewerID uuid.UUID, decision, comment string) (string, error) {
+	const op = "repository.ReviewRepo.Decide"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+	defer tx.Rollback()
+
+	// Locking the visualization serializes concurrent decisions, so exactly
+	// one of them sees the last outstanding approval.
+	var status string
+	err = tx.GetContext(ctx, &status, "SELECT status FROM visualizations WHERE id = $1 AND org_id = $2 FOR UPDATE", visualizationID, orgID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, ErrVisualizationNotFound)
+		}
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+	if status != models.StatusInReview {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidTransition)
+	}
+
+	var commentArg *string
+	if comment != "" {
+		commentArg = &comment
+	}
+
+	res, err := tx.ExecContext(ctx, `
+  UPDATE visualization_reviews SET decision = $3, comment = $4, decided_at = NOW()
+  WHERE visualization_id = $1 AND reviewer_id = $2 AND decision IS NULL
+  `, visualizationID, reviewerID, decision, commentArg)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+	if err = checkAffected(res, fmt.Errorf("%s: %w", op, ErrNotPendingReviewer)); err != nil {
+		return "", err
+	}
+
+	switch decision {
+	case models.ReviewRejected:
+		status = models.StatusDraft
+	default:
+		var pending bool
+		err = tx.GetContext(ctx, &pending, `
+    SELECT EXISTS (SELECT 1 FROM visualization_reviews WHERE visualization_id = $1 AND decision IS DISTINCT FROM 'approved')
+    `, visualizationID)
+		if err != nil {
+			logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return "", fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+		}
+		if !pending {
+			status = models.StatusApproved
+		}
+	}
+
+	if status != models.StatusInReview {
+		if _, err = tx.ExecContext(ctx, "UPDATE visualizations SET status = $2 WHERE id = $1", visualizationID, status); err != nil {
+			logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return "", fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", fmt.Errorf("%s: %w", op, ErrFailedToUpdateReviews)
+	}
+
+	return status, nil
+}
+
+// GetAll returns the reviewers of the current review round.
+func (r *ReviewRepo) GetAll(ctx context.Context, visualizationID uuid.UUID) ([]models.VisualizationReview, error) {
+	const op = "repository.ReviewRepo.GetAll"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reviews := make([]models.VisualizationReview, 0)
+
+	query := fmt.Sprintf(`
+  SELECT r.visualization_id, r.reviewer_id, u.username, r.requested_by, r.decision, r.comment, r.requested_at, r.decided_at
+  FROM visualization_reviews r
+  JOIN users u ON u.id = r.reviewer_id
+  WHERE r.visualization_id = $1 AND %s
+  ORDER BY u.username
+  `, inActiveOrg)
+
+	if err = r.db.SelectContext(ctx, &reviews, query, visualizationID, orgID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrFailedToFetchReviews)
+	}
+
+	return reviews, nil
+}
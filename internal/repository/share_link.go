@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/db/replica"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrShareLinkNotFound          = errors.New("share link not found")
+	ErrFailedToCreateShareLink    = errors.New("failed to create share link")
+	ErrFailedToFetchShareLinks    = errors.New("failed to fetch share links")
+	ErrFailedToUpdateShareLink    = errors.New("failed to update share link")
+	ErrFailedToIncrementShareView = errors.New("failed to increment share link view count")
+)
+
+const shareLinkColumns = `id, visualization_id, share_id, name, password_hash, password_hash IS NOT NULL AS has_password,
+  expires_at, revoked_at, view_count, viewed_at, created_by, created_at`
+
+// inActiveOrg restricts statements on rows belonging to a visualization to
+// the active organization; $1 is the visualization ID and $2 the organization
+// ID.
+const inActiveOrg = "EXISTS (SELECT 1 FROM visualizations WHERE id = $1 AND org_id = $2)"
+
+// ShareLinkRepo sends GetByShareID through reads, so it may be served by a
+// replica.
+type ShareLinkRepo struct {
+	log   *slog.Logger
+	db    *sqlx.DB
+	reads *replica.Router
+}
+
+func NewShareLinkRepo(log *slog.Logger, db *sqlx.DB, reads *replica.Router) *ShareLinkRepo {
+	return &ShareLinkRepo{log: log, db: db, reads: reads}
+}
+
+func (r *ShareLinkRepo) Create(ctx context.Context, visualizationID uuid.UUID, dto dto.ShareLinkCreateDto, passwordHash *string, createdBy uuid.UUID) (models.ShareLink, error) {
+	const op = "repository.ShareLinkRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var link models.ShareLink
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return link, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := fmt.Sprintf(`
+  INSERT INTO share_links (visualization_id, name, password_hash, expires_at, created_by)
+  SELECT $1, $3, $4, $5, $6
+  WHERE %s
+  RETURNING %s
+  `, inActiveOrg, shareLinkColumns)
+
+	err = r.db.GetContext(ctx, &link, query, visualizationID, orgID, dto.Name, passwordHash, dto.ExpiresAt, createdBy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return link, fmt.Errorf("%s: %w", op, ErrVisualizationNotFound)
+		}
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return link, fmt.Errorf("%s: %w", op, ErrFailedToCreateShareLink)
+	}
+
+	return link, nil
+}
+
+func (r *ShareLinkRepo) GetAllForVisualization(ctx context.Context, visualizationID uuid.UUID) ([]models.ShareLink, error) {
+	const op = "repository.ShareLinkRepo.GetAllForVisualization"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	links := make([]models.ShareLink, 0)
+
+	query := fmt.Sprintf(`
+  SELECT %s FROM share_links
+  WHERE visualization_id = $1 AND %s
+  ORDER BY created_at DESC
+  `, shareLinkColumns, inActiveOrg)
+
+	if err = r.db.SelectContext(ctx, &links, query, visualizationID, orgID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrFailedToFetchShareLinks)
+	}
+
+	return links, nil
+}
+
+// GetByShareID is intentionally not scoped to an organization: it resolves
+// public share URLs. Revoked links are reported as not found; when served by
+// a replica, a revocation takes effect once it has been replicated.
+func (r *ShareLinkRepo) GetByShareID(ctx context.Context, shareID uuid.UUID) (models.ShareLink, error) {
+	const op = "repository.ShareLinkRepo.GetByShareID"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := fmt.Sprintf("SELECT %s FROM share_links WHERE share_id = $1 AND revoked_at IS NULL", shareLinkColumns)
+
+	link, err := replica.Read(ctx, r.reads, op, func(db *sqlx.DB) (models.ShareLink, error) {
+		var link models.ShareLink
+		err := db.GetContext(ctx, &link, query, shareID)
+		return link, err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return link, fmt.Errorf("%s: %w", op, ErrShareLinkNotFound)
+		}
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return link, fmt.Errorf("%s: %w", op, ErrFailedToFetchShareLinks)
+	}
+
+	return link, nil
+}
+
+// Rotate gives an active link a new share ID, invalidating the old URL and
+// every viewer cookie issued for it.
+func (r *ShareLinkRepo) Rotate(ctx context.Context, visualizationID, linkID uuid.UUID) (uuid.UUID, error) {
+	const op = "repository.ShareLinkRepo.Rotate"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := fmt.Sprintf(`
+  UPDATE share_links SET share_id = gen_random_uuid()
+  WHERE visualization_id = $1 AND %s AND id = $3 AND revoked_at IS NULL
+  RETURNING share_id
+  `, inActiveOrg)
+
+	var shareID uuid.UUID
+	if err = r.db.GetContext(ctx, &shareID, query, visualizationID, orgID, linkID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrShareLinkNotFound)
+		}
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToUpdateShareLink)
+	}
+
+	return shareID, nil
+}
+
+func (r *ShareLinkRepo) Revoke(ctx context.Context, visualizationID, linkID uuid.UUID) error {
+	const op = "repository.ShareLinkRepo.Revoke"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := fmt.Sprintf(`
+  UPDATE share_links SET revoked_at = NOW()
+  WHERE visualization_id = $1 AND %s AND id = $3 AND revoked_at IS NULL
+  `, inActiveOrg)
+
+	res, err := r.db.ExecContext(ctx, query, visualizationID, orgID, linkID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateShareLink)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrShareLinkNotFound))
+}
+
+// AddViewCounts adds buffered view counts in one statement. It is called on
+// behalf of public share pages and therefore not scoped to an organization.
+func (r *ShareLinkRepo) AddViewCounts(ctx context.Context, counts map[uuid.UUID]int) error {
+	const op = "repository.ShareLinkRepo.AddViewCounts"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	ids, ns := viewCountArrays(counts)
+
+	query := `
+  WITH c AS (SELECT * FROM unnest($1::uuid[], $2::bigint[]) AS c(id, n)),
+  locked AS (SELECT l.id FROM share_links l JOIN c ON c.id = l.id ORDER BY l.id FOR UPDATE)
+  UPDATE share_links l SET view_count = l.view_count + c.n, viewed_at = NOW()
+  FROM c
+  WHERE l.id = c.id AND l.id IN (SELECT id FROM locked)
+  `
+
+	if _, err := r.db.ExecContext(ctx, query, ids, ns); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToIncrementShareView)
+	}
+
+	return nil
+}
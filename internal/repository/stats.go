@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrFailedToCountEntities = errors.New("failed to count entities")
+
+type StatsRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewStatsRepo(log *slog.Logger, db *sqlx.DB) *StatsRepo {
+	return &StatsRepo{log: log, db: db}
+}
+
+// CountEntities counts across all organizations for monitoring; it must not
+// be exposed through the API.
+func (r *StatsRepo) CountEntities(ctx context.Context) (models.EntityCounts, error) {
+	const op = "repository.StatsRepo.CountEntities"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var counts models.EntityCounts
+
+	query := `
+  SELECT
+    (SELECT COUNT(*) FROM visualizations) AS visualizations,
+    (SELECT COUNT(*) FROM visualizations WHERE is_published) AS published_visualizations,
+    (SELECT COUNT(*) FROM templates) AS templates,
+    (SELECT COUNT(*) FROM users WHERE is_active) AS users
+  `
+
+	if err := r.db.GetContext(ctx, &counts, query); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return counts, fmt.Errorf("%s: %w", op, ErrFailedToCountEntities)
+	}
+
+	return counts, nil
+}
@@ -8,7 +8,12 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/db/replica"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
 	"visualizer-go/internal/models"
 
 	"github.com/google/uuid"
@@ -22,24 +27,35 @@ var (
 	ErrFailedToUpdateTemplate = errors.New("failed to update template")
 )
 
+// TemplateRepo sends GetAll and GetByID through reads, so they may be
+// served by a replica.
 type TemplateRepo struct {
-	log *slog.Logger
-	db  *sqlx.DB
+	log   *slog.Logger
+	db    *sqlx.DB
+	reads *replica.Router
 }
 
-func NewTemplateRepo(log *slog.Logger, db *sqlx.DB) *TemplateRepo {
-	return &TemplateRepo{log: log, db: db}
+func NewTemplateRepo(log *slog.Logger, db *sqlx.DB, reads *replica.Router) *TemplateRepo {
+	return &TemplateRepo{log: log, db: db, reads: reads}
 }
 
 func (r *TemplateRepo) GetAll(ctx context.Context, withCanvases bool) ([]models.Template, error) {
 	const op = "repository.TemplateRepo.GetAll"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-	var templates []models.Template
 	// var rowsCount int
 
 	q := `
   SELECT 
     t.id,
+    t.org_id,
     t.name,
     t.description,
     t.is_deleted,
@@ -62,17 +78,22 @@ func (r *TemplateRepo) GetAll(ctx context.Context, withCanvases bool) ([]models.
   LEFT JOIN 
     visualizations v ON v.template_id = t.id
   WHERE 
-    t.is_deleted = false
+    t.org_id = $1 AND t.is_deleted = false
   GROUP BY 
-    t.id, t.name, t.description, t.is_deleted, t.updated_at, t.created_at
+    t.id, t.org_id, t.name, t.description, t.is_deleted, t.updated_at, t.created_at
   ORDER BY 
     t.updated_at DESC;
   `
 	// LIMIT $1 OFFSET $2;
 
-	err := r.db.SelectContext(ctx, &templates, q)
+	templates, err := replica.Read(ctx, r.reads, op, func(db *sqlx.DB) ([]models.Template, error) {
+		var templates []models.Template
+		err := db.SelectContext(ctx, &templates, q, orgID)
+		return templates, err
+	})
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", op, ErrTemplatesNotFound)
 		}
@@ -96,11 +117,23 @@ func (r *TemplateRepo) GetAll(ctx context.Context, withCanvases bool) ([]models.
 
 func (r *TemplateRepo) GetByID(ctx context.Context, templateID uuid.UUID) (models.Template, error) {
 	const op = "repository.TemplateRepo.GetByID"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
-	var template models.Template
-	err := r.db.GetContext(ctx, &template, "SELECT * FROM templates WHERE id = $1 AND is_deleted = FALSE", templateID)
+	orgID, err := activeOrgID(ctx)
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+		return models.Template{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	template, err := replica.Read(ctx, r.reads, op, func(db *sqlx.DB) (models.Template, error) {
+		var template models.Template
+		err := db.GetContext(ctx, &template, "SELECT * FROM templates WHERE id = $1 AND org_id = $2 AND is_deleted = FALSE", templateID, orgID)
+		return template, err
+	})
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return template, fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
 		}
@@ -112,16 +145,23 @@ func (r *TemplateRepo) GetByID(ctx context.Context, templateID uuid.UUID) (model
 
 func (r *TemplateRepo) Create(ctx context.Context, dto dto.TemplateCreateDto) (uuid.UUID, error) {
 	const op = "repository.TemplateRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
 	var templateID uuid.UUID
 
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	// TODO: вынести преобразование на уровень service
 	var canvasesJson interface{}
-	var err error
 	if dto.Canvases != nil {
 		canvasesJson, err = json.Marshal(dto.Canvases)
 		if err != nil {
-			r.log.Error(fmt.Sprintf("%s: failed to marshal canvases: %v", op, err))
+			logger.FromContext(ctx, r.log).Error("failed to marshal canvases", logger.Op(op), logger.Err(err))
 			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateTemplate)
 		}
 	} else {
@@ -129,10 +169,11 @@ func (r *TemplateRepo) Create(ctx context.Context, dto dto.TemplateCreateDto) (u
 		canvasesJson = nil
 	}
 
-	err = r.db.GetContext(ctx, &templateID, "INSERT INTO templates (name, description, canvases) VALUES ($1, $2, $3) RETURNING id",
-		dto.Name, dto.Description, canvasesJson)
+	err = r.db.GetContext(ctx, &templateID, "INSERT INTO templates (org_id, name, description, canvases) VALUES ($1, $2, $3, $4) RETURNING id",
+		orgID, dto.Name, dto.Description, canvasesJson)
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateTemplate)
 	}
 
@@ -141,6 +182,14 @@ func (r *TemplateRepo) Create(ctx context.Context, dto dto.TemplateCreateDto) (u
 
 func (r *TemplateRepo) Update(ctx context.Context, templateID uuid.UUID, dto dto.TemplateUpdateDto) error {
 	const op = "repository.TemplateRepo.Update"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	setValues := make([]string, 0)
 	args := make([]interface{}, 0)
@@ -162,7 +211,7 @@ func (r *TemplateRepo) Update(ctx context.Context, templateID uuid.UUID, dto dto
 		// Преобразуем canvases в строку JSON
 		canvasesJson, err := json.Marshal(dto.Canvases)
 		if err != nil {
-			r.log.Error(fmt.Sprintf("%s: failed to marshal canvases: %v", op, err))
+			logger.FromContext(ctx, r.log).Error("failed to marshal canvases", logger.Op(op), logger.Err(err))
 			return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTemplate)
 		}
 		setValues = append(setValues, fmt.Sprintf("canvases=$%d", argId))
@@ -180,13 +229,15 @@ func (r *TemplateRepo) Update(ctx context.Context, templateID uuid.UUID, dto dto
 
 	setQuery := strings.Join(setValues, ", ")
 
-	q := fmt.Sprintf("UPDATE templates SET %s WHERE id=$%d AND is_deleted = FALSE", setQuery, argId)
-	args = append(args, templateID)
+	q := fmt.Sprintf("UPDATE templates SET %s WHERE id=$%d AND org_id=$%d AND is_deleted = FALSE", setQuery, argId, argId+1)
+	args = append(args, templateID, orgID)
 
-	if _, err := r.db.ExecContext(ctx, q, args...); err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTemplate)
 	}
 
-	return nil
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrTemplateNotFound))
 }
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotPending     = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorCodeInvalid    = errors.New("invalid two-factor code")
+	ErrFailedToUpdateTwoFactor = errors.New("failed to update two-factor settings")
+)
+
+type TwoFactorRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewTwoFactorRepo(log *slog.Logger, db *sqlx.DB) *TwoFactorRepo {
+	return &TwoFactorRepo{log: log, db: db}
+}
+
+// Get loads the user together with the (encrypted) TOTP settings that the
+// regular user queries leave out.
+func (r *TwoFactorRepo) Get(ctx context.Context, userID uuid.UUID) (models.User, error) {
+	const op = "repository.TwoFactorRepo.Get"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var user models.User
+
+	query := "SELECT " + userColumns + ", totp_secret, totp_last_step FROM users WHERE id=$1"
+
+	if err := r.db.GetContext(ctx, &user, query, userID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return user, fmt.Errorf("%s: %w", op, ErrFailedToFetchUsers)
+	}
+
+	return user, nil
+}
+
+// SetSecret stores a pending secret. It is only used for codes once Enable
+// confirms that the user's authenticator produces matching codes.
+func (r *TwoFactorRepo) SetSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	const op = "repository.TwoFactorRepo.SetSecret"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE users SET totp_secret=$1, totp_last_step=NULL WHERE id=$2 AND totp_enabled=FALSE", secret, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrTwoFactorAlreadyEnabled))
+}
+
+// Enable turns on the pending secret and replaces the user's recovery codes.
+// step is the time step of the code used for confirmation, so it cannot be
+// replayed at login.
+func (r *TwoFactorRepo) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	const op = "repository.TwoFactorRepo.Enable"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+  UPDATE users SET totp_enabled=TRUE, totp_last_step=$1
+  WHERE id=$2 AND totp_enabled=FALSE AND totp_secret IS NOT NULL
+  `, step, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+	if err = checkAffected(res, ErrTwoFactorNotPending); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = r.replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+
+	return nil
+}
+
+// Disable removes the secret and every recovery code of the user.
+func (r *TwoFactorRepo) Disable(ctx context.Context, userID uuid.UUID) error {
+	const op = "repository.TwoFactorRepo.Disable"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE users SET totp_secret=NULL, totp_enabled=FALSE, totp_last_step=NULL WHERE id=$1", userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+	if err = checkAffected(res, ErrUserNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = r.replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+
+	return nil
+}
+
+// UseStep records step as the last accepted time step. It fails when a code
+// of the same or a later step was already used, which rejects replays.
+func (r *TwoFactorRepo) UseStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	const op = "repository.TwoFactorRepo.UseStep"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	res, err := r.db.ExecContext(ctx, `
+  UPDATE users SET totp_last_step=$1
+  WHERE id=$2 AND totp_enabled=TRUE AND (totp_last_step IS NULL OR totp_last_step < $1)
+  `, step, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrTwoFactorCodeInvalid))
+}
+
+// UseRecoveryCode marks an unused recovery code as used.
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	const op = "repository.TwoFactorRepo.UseRecoveryCode"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE user_recovery_codes SET used_at=NOW() WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL",
+		userID, codeHash)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateTwoFactor)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrTwoFactorCodeInvalid))
+}
+
+func (r *TwoFactorRepo) replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id=$1", userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
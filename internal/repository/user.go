@@ -2,25 +2,35 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"log/slog"
 	"strings"
+	"time"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
 	"visualizer-go/internal/models"
 )
 
 var (
 	ErrFailedToCreateUser = errors.New("failed to create user")
 	ErrFailedToUpdateUser = errors.New("failed to update user")
+	ErrFailedToDeleteUser = errors.New("failed to delete user")
 	ErrUserNotFound       = errors.New("user not found")
 	ErrFailedToFetchUsers = errors.New("failed to fetch users")
 	ErrFailedToLogin      = errors.New("failed to login")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	ErrReassignTargetNotMember = errors.New("new owner is not a member of every organization the user has visualizations in")
 )
 
+const userColumns = "id, username, email, role, is_active, session_version, totp_enabled, created_at, updated_at"
+
 type UserRepo struct {
 	log *slog.Logger
 	db  *sqlx.DB
@@ -30,14 +40,51 @@ func NewUserRepo(log *slog.Logger, db *sqlx.DB) *UserRepo {
 	return &UserRepo{log: log, db: db}
 }
 
+func (r *UserRepo) GetAll(ctx context.Context, search string, limit, offset int) ([]models.User, int, error) {
+	const op = "repository.UserRepo.GetAll"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	users := make([]models.User, 0)
+	pattern := "%" + escapeLike(strings.ToLower(search)) + "%"
+
+	q := fmt.Sprintf(`
+  SELECT %s
+  FROM users
+  WHERE LOWER(username) LIKE $1
+  ORDER BY username
+  LIMIT $2 OFFSET $3
+  `, userColumns)
+
+	if err := r.db.SelectContext(ctx, &users, q, pattern, limit, offset); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, 0, fmt.Errorf("%s: %w", op, ErrFailedToFetchUsers)
+	}
+
+	var rowCount int
+	if err := r.db.GetContext(ctx, &rowCount, "SELECT COUNT(*) FROM users WHERE LOWER(username) LIKE $1", pattern); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, 0, fmt.Errorf("%s: %w", op, ErrFailedToFetchUsers)
+	}
+
+	return users, rowCount, nil
+}
+
 func (r *UserRepo) GetByID(ctx context.Context, userID uuid.UUID) (models.User, error) {
 	const op = "repository.UserRepo.GetByID"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
 	var user models.User
-	err := r.db.GetContext(ctx, &user, "SELECT id, username, role, created_at, updated_at FROM users WHERE id=$1", userID)
+	err := r.db.GetContext(ctx, &user, "SELECT "+userColumns+" FROM users WHERE id=$1", userID)
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %v", op, err))
-		if err.Error() == "sql: no rows in result set" {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
 			return user, fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 		return user, fmt.Errorf("%s: %w", op, ErrFailedToFetchUsers)
@@ -48,12 +95,16 @@ func (r *UserRepo) GetByID(ctx context.Context, userID uuid.UUID) (models.User,
 
 func (r *UserRepo) GetByUsername(ctx context.Context, username string) (models.User, error) {
 	const op = "repository.UserRepo.GetByUsername"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
 	var user models.User
 	err := r.db.GetContext(ctx, &user, "SELECT * FROM users WHERE username=$1", username)
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %v", op, err))
-		if err.Error() == "sql: no rows in result set" {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
 			return user, fmt.Errorf("%s: %w", op, ErrUserNotFound)
 		}
 		return user, fmt.Errorf("%s: %w", op, ErrFailedToFetchUsers)
@@ -62,21 +113,145 @@ func (r *UserRepo) GetByUsername(ctx context.Context, username string) (models.U
 	return user, nil
 }
 
-func (r *UserRepo) Create(ctx context.Context, dto dto.UserCreateDto) error {
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	const op = "repository.UserRepo.GetByEmail"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var user models.User
+	err := r.db.GetContext(ctx, &user, "SELECT "+userColumns+" FROM users WHERE LOWER(email)=LOWER($1)", email)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		return user, fmt.Errorf("%s: %w", op, ErrFailedToFetchUsers)
+	}
+
+	return user, nil
+}
+
+func (r *UserRepo) Create(ctx context.Context, dto dto.UserCreateDto) (uuid.UUID, error) {
 	const op = "repository.UserRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var userID uuid.UUID
+	err := r.db.GetContext(ctx, &userID, "INSERT INTO users (username, email, password_hash) VALUES ($1, $2, $3) RETURNING id",
+		dto.Username, dto.Email, dto.Password)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if isPgError(err, errUniqueViolation) {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrUsernameOrEmailAlreadyUsed)
+		}
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateUser)
+	}
+
+	return userID, nil
+}
+
+func (r *UserRepo) GetByOIDCSubject(ctx context.Context, issuer, subject string) (models.User, error) {
+	const op = "repository.UserRepo.GetByOIDCSubject"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
-	_, err := r.db.ExecContext(ctx, "INSERT INTO users (username, password_hash) VALUES ($1, $2)",
-		dto.Username, dto.Password)
+	var user models.User
+
+	err := r.db.GetContext(ctx, &user, "SELECT "+userColumns+" FROM users WHERE oidc_issuer=$1 AND oidc_subject=$2", issuer, subject)
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %v", op, err))
-		return fmt.Errorf("%s: %w", op, ErrFailedToCreateUser)
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return user, fmt.Errorf("%s: %w", op, ErrFailedToFetchUsers)
 	}
 
-	return nil
+	return user, nil
+}
+
+// CreateOIDC provisions a user on their first single sign-on and, when
+// dto.OrgID is set, adds them to that organization in the same transaction.
+func (r *UserRepo) CreateOIDC(ctx context.Context, dto dto.OIDCUserDto) (models.User, error) {
+	const op = "repository.UserRepo.CreateOIDC"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	var user models.User
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return user, fmt.Errorf("%s: %w", op, ErrFailedToCreateUser)
+	}
+	defer tx.Rollback()
+
+	query := `
+  INSERT INTO users (username, email, password_hash, role, oidc_issuer, oidc_subject)
+  VALUES ($1, $2, $3, $4, $5, $6)
+  RETURNING ` + userColumns
+
+	err = tx.GetContext(ctx, &user, query,
+		dto.Username, dto.Email, dto.PasswordHash, dto.Role, dto.Issuer, dto.Subject)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if isPgError(err, errUniqueViolation) {
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrUsernameOrEmailAlreadyUsed)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrFailedToCreateUser)
+	}
+
+	if dto.OrgID != uuid.Nil {
+		_, err = tx.ExecContext(ctx, "INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)",
+			dto.OrgID, user.ID, dto.Role)
+		if err != nil {
+			logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return models.User{}, fmt.Errorf("%s: %w", op, ErrFailedToCreateUser)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return models.User{}, fmt.Errorf("%s: %w", op, ErrFailedToCreateUser)
+	}
+
+	return user, nil
+}
+
+// LinkOIDC attaches an IdP identity to an existing user that has none yet.
+func (r *UserRepo) LinkOIDC(ctx context.Context, userID uuid.UUID, issuer, subject string) error {
+	const op = "repository.UserRepo.LinkOIDC"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE users SET oidc_issuer=$1, oidc_subject=$2, updated_at=NOW() WHERE id=$3 AND oidc_subject IS NULL",
+		issuer, subject, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateUser)
+	}
+
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrUserNotFound))
 }
 
 func (r *UserRepo) Update(ctx context.Context, userID uuid.UUID, dto dto.UserUpdateDto) error {
 	const op = "repository.UserRepo.Update"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
 	setValues := make([]string, 0)
 	args := make([]interface{}, 0)
@@ -88,15 +263,100 @@ func (r *UserRepo) Update(ctx context.Context, userID uuid.UUID, dto dto.UserUpd
 		argId++
 	}
 
+	setValues = append(setValues, "updated_at=NOW()")
+
 	setQuery := strings.Join(setValues, ", ")
 
 	q := fmt.Sprintf("UPDATE users SET %s WHERE id=$%d", setQuery, argId)
 	args = append(args, userID)
 
 	if _, err := r.db.ExecContext(ctx, q, args...); err != nil {
-		r.log.Error(fmt.Sprintf("%s: %v", op, err))
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateUser)
+	}
+
+	return nil
+}
+
+// SetActive activates or deactivates a user. Deactivation also bumps the
+// session version, revoking every token issued to the user so far.
+func (r *UserRepo) SetActive(ctx context.Context, userID uuid.UUID, active bool) error {
+	const op = "repository.UserRepo.SetActive"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	q := "UPDATE users SET is_active=$1, updated_at=NOW() WHERE id=$2"
+	if !active {
+		q = "UPDATE users SET is_active=$1, session_version=session_version+1, updated_at=NOW() WHERE id=$2"
+	}
+
+	res, err := r.db.ExecContext(ctx, q, active, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateUser)
 	}
 
+	return checkAffected(res, fmt.Errorf("%s: %w", op, ErrUserNotFound))
+}
+
+// Delete removes a user and hands their visualizations over to another user
+// within a single transaction. The new owner must belong to every
+// organization those visualizations are in, so no visualization ends up
+// owned by an outsider.
+func (r *UserRepo) Delete(ctx context.Context, userID uuid.UUID, reassignTo uuid.UUID) error {
+	const op = "repository.UserRepo.Delete"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToDeleteUser)
+	}
+	defer tx.Rollback()
+
+	var outside int
+	err = tx.GetContext(ctx, &outside, `
+  SELECT COUNT(*) FROM visualizations v
+  WHERE v.user_id = $2
+    AND NOT EXISTS (SELECT 1 FROM organization_members m WHERE m.org_id = v.org_id AND m.user_id = $1)
+  `, reassignTo, userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToDeleteUser)
+	}
+	if outside > 0 {
+		return fmt.Errorf("%s: %w", op, ErrReassignTargetNotMember)
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE visualizations SET user_id=$1 WHERE user_id=$2", reassignTo, userID); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToDeleteUser)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id=$1", userID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToDeleteUser)
+	}
+
+	if err = checkAffected(res, fmt.Errorf("%s: %w", op, ErrUserNotFound)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToDeleteUser)
+	}
+
 	return nil
 }
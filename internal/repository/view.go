@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrFailedToRecordView     = errors.New("failed to record view")
+	ErrFailedToFetchAnalytics = errors.New("failed to fetch analytics")
+)
+
+// topReferrers is the number of referrers returned with analytics.
+const topReferrers = 10
+
+type ViewRepo struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewViewRepo(log *slog.Logger, db *sqlx.DB) *ViewRepo {
+	return &ViewRepo{log: log, db: db}
+}
+
+// Record stores view unless the same viewer already viewed the visualization
+// within window, and reports whether it was stored. It is called from public
+// pages and therefore not scoped to an organization.
+func (r *ViewRepo) Record(ctx context.Context, view models.VisualizationView, window time.Duration) (bool, error) {
+	const op = "repository.ViewRepo.Record"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `
+  INSERT INTO visualization_views (visualization_id, share_link_id, viewer_hash, referrer, user_agent_class)
+  SELECT $1, $2, $3, $4, $5
+  WHERE NOT EXISTS (
+    SELECT 1 FROM visualization_views
+    WHERE visualization_id = $1 AND viewer_hash = $3 AND viewed_at > NOW() - make_interval(secs => $6)
+  )
+  `
+
+	res, err := r.db.ExecContext(ctx, query, view.VisualizationID, view.ShareLinkID, view.ViewerHash,
+		view.Referrer, view.UserAgentClass, window.Seconds())
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return false, fmt.Errorf("%s: %w", op, ErrFailedToRecordView)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n > 0, nil
+}
+
+// GetAnalytics aggregates the views of a visualization in [from, to) into
+// buckets of one interval (hour, day, week or month). Empty buckets are
+// included so the series can be plotted as is.
+func (r *ViewRepo) GetAnalytics(ctx context.Context, visualizationID uuid.UUID, from, to time.Time, interval string) (models.ViewAnalytics, error) {
+	const op = "repository.ViewRepo.GetAnalytics"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	analytics := models.ViewAnalytics{From: from, To: to, Interval: interval}
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return analytics, fmt.Errorf("%s: %w", op, err)
+	}
+
+	totals := fmt.Sprintf(`
+  SELECT COUNT(*) AS views, COUNT(DISTINCT viewer_hash) AS viewers
+  FROM visualization_views
+  WHERE visualization_id = $1 AND viewed_at >= $3 AND viewed_at < $4 AND %s
+  `, inActiveOrg)
+
+	row := r.db.QueryRowxContext(ctx, totals, visualizationID, orgID, from, to)
+	if err = row.Scan(&analytics.Views, &analytics.Viewers); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return analytics, fmt.Errorf("%s: %w", op, ErrFailedToFetchAnalytics)
+	}
+
+	series := fmt.Sprintf(`
+  SELECT b.bucket, COUNT(v.id) AS views, COUNT(DISTINCT v.viewer_hash) AS viewers
+  FROM generate_series(
+    date_trunc($3, $4::timestamptz),
+    $5::timestamptz - INTERVAL '1 microsecond',
+    ('1 ' || $3)::interval) AS b(bucket)
+  LEFT JOIN visualization_views v
+    ON v.visualization_id = $1
+   AND v.viewed_at >= GREATEST(b.bucket, $4::timestamptz)
+   AND v.viewed_at < LEAST(b.bucket + ('1 ' || $3)::interval, $5::timestamptz)
+  WHERE %s
+  GROUP BY b.bucket
+  ORDER BY b.bucket
+  `, inActiveOrg)
+
+	analytics.Series = make([]models.ViewBucket, 0)
+	if err = r.db.SelectContext(ctx, &analytics.Series, series, visualizationID, orgID, interval, from, to); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return analytics, fmt.Errorf("%s: %w", op, ErrFailedToFetchAnalytics)
+	}
+
+	referrers := fmt.Sprintf(`
+  SELECT referrer, COUNT(*) AS views
+  FROM visualization_views
+  WHERE visualization_id = $1 AND viewed_at >= $3 AND viewed_at < $4 AND referrer IS NOT NULL AND %s
+  GROUP BY referrer
+  ORDER BY views DESC, referrer
+  LIMIT $5
+  `, inActiveOrg)
+
+	analytics.Referrers = make([]models.ReferrerCount, 0)
+	if err = r.db.SelectContext(ctx, &analytics.Referrers, referrers, visualizationID, orgID, from, to, topReferrers); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return analytics, fmt.Errorf("%s: %w", op, ErrFailedToFetchAnalytics)
+	}
+
+	return analytics, nil
+}
@@ -8,11 +8,17 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/db/replica"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
 	"visualizer-go/internal/models"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 var (
@@ -21,32 +27,65 @@ var (
 	ErrFailedToCreateVisualization             = errors.New("failed to create visualization")
 	ErrFailedToUpdateVisualization             = errors.New("failed to update visualization")
 	ErrFailedToIncrementViewCountVisualization = errors.New("failed to increment view count visualization")
+	ErrInvalidTransition                       = errors.New("transition is not allowed from the current state")
 )
 
 // TODO: УБРАТЬ OP из возврата ошибок
 
+// firstShareID selects the oldest active share link of the visualization v
+// for the deprecated Visualization.ShareID.
+const firstShareID = `(
+  SELECT l.share_id FROM share_links l
+  WHERE l.visualization_id = v.id AND l.revoked_at IS NULL AND (l.expires_at IS NULL OR l.expires_at > NOW())
+  ORDER BY l.created_at, l.id
+  LIMIT 1
+) AS share_id`
+
+// publishedSnapshot freezes the working copy of a visualization row as the
+// content served to viewers.
+const publishedSnapshot = `jsonb_build_object(
+        'name', name,
+        'description', description,
+        'client', client,
+        'canvases', canvases,
+        'template_id', template_id,
+        'tenant', tenant)`
+
+// VisualizationRepo sends GetAll, GetByTemplateID, GetByID and
+// GetPublishedByID through reads, so they may be served by a replica.
 type VisualizationRepo struct {
-	log *slog.Logger
-	db  *sqlx.DB
+	log   *slog.Logger
+	db    *sqlx.DB
+	reads *replica.Router
 }
 
-func NewVisualizationRepo(log *slog.Logger, db *sqlx.DB) *VisualizationRepo {
-	return &VisualizationRepo{log: log, db: db}
+func NewVisualizationRepo(log *slog.Logger, db *sqlx.DB, reads *replica.Router) *VisualizationRepo {
+	return &VisualizationRepo{log: log, db: db, reads: reads}
 }
 
 func (r *VisualizationRepo) GetAll(ctx context.Context) ([]models.Visualization, error) {
 	const op = "repository.VisualizationRepo.GetAll"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
-	var visualizations []models.Visualization
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	query := `
 	SELECT 
 			v.id, 
+			v.org_id,
 			v.name, 
 			v.description,
 			v.client,
 			v.is_published, 
-			v.share_id,
+      v.status,
+      v.published_at,
+      v.publish_at,
+      v.unpublish_at,
       v.template_id,
 			v.updated_at, 
 			v.created_at, 
@@ -54,16 +93,23 @@ func (r *VisualizationRepo) GetAll(ctx context.Context) ([]models.Visualization,
       v.viewed_at,
 			v.user_id,
 			u.username AS username,
-      t.name AS template_name
+      t.name AS template_name,
+      ` + firstShareID + `
 	FROM visualizations v
 	LEFT JOIN users u ON v.user_id = u.id
   LEFT JOIN templates t ON v.template_id = t.id
+	WHERE v.org_id = $1
 	ORDER BY v.updated_at DESC
 	`
 
-	err := r.db.SelectContext(ctx, &visualizations, query)
+	visualizations, err := replica.Read(ctx, r.reads, op, func(db *sqlx.DB) ([]models.Visualization, error) {
+		var visualizations []models.Visualization
+		err := db.SelectContext(ctx, &visualizations, query, orgID)
+		return visualizations, err
+	})
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%w", ErrVisualizationsNotFound)
 		}
@@ -75,21 +121,32 @@ func (r *VisualizationRepo) GetAll(ctx context.Context) ([]models.Visualization,
 
 func (r *VisualizationRepo) GetByTemplateID(ctx context.Context, templateID uuid.UUID) ([]models.Visualization, error) {
 	const op = "repository.VisualizationRepo.GetByTemplateID"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
-	var visualizations []models.Visualization
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	query := `
   SELECT 
     id, 
     name
   FROM visualizations
-  WHERE template_id = $1
+  WHERE template_id = $1 AND org_id = $2
   ORDER BY updated_at DESC;
   `
 
-	err := r.db.SelectContext(ctx, &visualizations, query, templateID)
+	visualizations, err := replica.Read(ctx, r.reads, op, func(db *sqlx.DB) ([]models.Visualization, error) {
+		var visualizations []models.Visualization
+		err := db.SelectContext(ctx, &visualizations, query, templateID, orgID)
+		return visualizations, err
+	})
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%w", ErrVisualizationsNotFound)
 		}
@@ -101,11 +158,23 @@ func (r *VisualizationRepo) GetByTemplateID(ctx context.Context, templateID uuid
 
 func (r *VisualizationRepo) GetByID(ctx context.Context, visualizationID uuid.UUID) (models.Visualization, error) {
 	const op = "repository.VisualizationRepo.GetByID"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return models.Visualization{}, fmt.Errorf("%s: %w", op, err)
+	}
 
-	var visualization models.Visualization
-	err := r.db.GetContext(ctx, &visualization, "SELECT * FROM visualizations WHERE id = $1", visualizationID)
+	visualization, err := replica.Read(ctx, r.reads, op, func(db *sqlx.DB) (models.Visualization, error) {
+		var visualization models.Visualization
+		err := db.GetContext(ctx, &visualization, "SELECT v.*, "+firstShareID+" FROM visualizations v WHERE v.id = $1 AND v.org_id = $2", visualizationID, orgID)
+		return visualization, err
+	})
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return visualization, fmt.Errorf("%w", ErrVisualizationNotFound)
 		}
@@ -115,13 +184,47 @@ func (r *VisualizationRepo) GetByID(ctx context.Context, visualizationID uuid.UU
 	return visualization, nil
 }
 
-func (r *VisualizationRepo) GetByShareID(ctx context.Context, shareID uuid.UUID) (models.Visualization, error) {
-	const op = "repository.VisualizationRepo.GetByShareID"
+// GetPublishedByID is intentionally not scoped to an organization: it serves
+// share links, where a valid link itself grants read access. The content is
+// taken from the snapshot frozen at publish time, not the working copy.
+func (r *VisualizationRepo) GetPublishedByID(ctx context.Context, visualizationID uuid.UUID) (models.Visualization, error) {
+	const op = "repository.VisualizationRepo.GetPublishedByID"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `
+  SELECT
+    v.id,
+    v.org_id,
+    s.name,
+    s.description,
+    s.client,
+    s.canvases,
+    s.template_id,
+    s.tenant,
+    v.is_published,
+    v.status,
+    v.user_id,
+    v.view_count,
+    v.viewed_at,
+    v.published_at,
+    v.published_by,
+    v.created_at,
+    v.published_at AS updated_at
+  FROM visualizations v,
+    jsonb_to_record(v.published_snapshot) AS s(name TEXT, description TEXT, client TEXT, canvases JSONB, template_id UUID, tenant TEXT)
+  WHERE v.id = $1 AND v.is_published = TRUE
+  `
 
-	var visualization models.Visualization
-	err := r.db.GetContext(ctx, &visualization, "SELECT * FROM visualizations WHERE share_id = $1 AND is_published = TRUE", shareID)
+	visualization, err := replica.Read(ctx, r.reads, op, func(db *sqlx.DB) (models.Visualization, error) {
+		var visualization models.Visualization
+		err := db.GetContext(ctx, &visualization, query, visualizationID)
+		return visualization, err
+	})
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return visualization, fmt.Errorf("%w", ErrVisualizationNotFound)
 		}
@@ -132,18 +235,25 @@ func (r *VisualizationRepo) GetByShareID(ctx context.Context, shareID uuid.UUID)
 
 func (r *VisualizationRepo) Create(ctx context.Context, dto dto.VisualizationCreateDto) (uuid.UUID, error) {
 	const op = "repository.VisualizationRepo.Create"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
 	var visualizationID uuid.UUID
 
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	// Преобразование поля Canvases в JSON
 	var canvasesJson interface{}
-	var err error
 
 	// Если Canvases не nil, сериализуем в JSON
 	if dto.Canvases != nil {
 		canvasesJson, err = json.Marshal(dto.Canvases)
 		if err != nil {
-			r.log.Error(fmt.Sprintf("%s: failed to marshal canvases: %v", op, err))
+			logger.FromContext(ctx, r.log).Error("failed to marshal canvases", logger.Op(op), logger.Err(err))
 			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateVisualization)
 		}
 	} else {
@@ -151,11 +261,21 @@ func (r *VisualizationRepo) Create(ctx context.Context, dto dto.VisualizationCre
 		canvasesJson = nil
 	}
 
-	// Вставка данных в таблицу visualizations
-	err = r.db.GetContext(ctx, &visualizationID, "INSERT INTO visualizations (name, user_id, canvases, template_id) VALUES ($1, $2, $3, $4) RETURNING id",
-		dto.Name, dto.UserID, canvasesJson, dto.TemplateID)
+	// Вставка данных в таблицу visualizations; шаблон должен принадлежать той же организации
+	query := `
+  INSERT INTO visualizations (org_id, name, user_id, canvases, template_id)
+  SELECT $1, $2, $3, $4, $5
+  WHERE $5::uuid IS NULL OR EXISTS (SELECT 1 FROM templates WHERE id = $5 AND org_id = $1)
+  RETURNING id
+  `
+
+	err = r.db.GetContext(ctx, &visualizationID, query, orgID, dto.Name, dto.UserID, canvasesJson, dto.TemplateID)
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, ErrTemplateNotFound)
+		}
 		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToCreateVisualization)
 	}
 
@@ -164,13 +284,19 @@ func (r *VisualizationRepo) Create(ctx context.Context, dto dto.VisualizationCre
 
 func (r *VisualizationRepo) Update(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationUpdateDto) error {
 	const op = "repository.VisualizationRepo.Update"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	setValues := make([]string, 0)
 	args := make([]interface{}, 0)
 	argId := 1
 
-	fmt.Println("dto", dto)
-
 	if dto.Name != nil {
 		setValues = append(setValues, fmt.Sprintf("name=$%d", argId))
 		args = append(args, *dto.Name)
@@ -189,16 +315,10 @@ func (r *VisualizationRepo) Update(ctx context.Context, visualizationID uuid.UUI
 		argId++
 	}
 
-	if dto.IsPublished != nil {
-		setValues = append(setValues, fmt.Sprintf("is_published=$%d", argId))
-		args = append(args, *dto.IsPublished)
-		argId++
-	}
-
 	if dto.Canvases != nil {
 		canvasesJson, err := json.Marshal(dto.Canvases)
 		if err != nil {
-			r.log.Error(fmt.Sprintf("%s: failed to marshal canvases: %v", op, err))
+			logger.FromContext(ctx, r.log).Error("failed to marshal canvases", logger.Op(op), logger.Err(err))
 			return fmt.Errorf("%s: %w", op, ErrFailedToUpdateVisualization)
 		}
 		setValues = append(setValues, fmt.Sprintf("canvases=$%d", argId))
@@ -207,6 +327,17 @@ func (r *VisualizationRepo) Update(ctx context.Context, visualizationID uuid.UUI
 	}
 
 	if dto.TemplateID != nil {
+		var templateExists bool
+		err = r.db.GetContext(ctx, &templateExists, "SELECT EXISTS (SELECT 1 FROM templates WHERE id = $1 AND org_id = $2)", *dto.TemplateID, orgID)
+		if err != nil {
+			logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return fmt.Errorf("%w", ErrFailedToUpdateVisualization)
+		}
+		if !templateExists {
+			return fmt.Errorf("%w", ErrTemplateNotFound)
+		}
+
 		setValues = append(setValues, fmt.Sprintf("template_id=$%d", argId))
 		args = append(args, *dto.TemplateID)
 		argId++
@@ -218,41 +349,252 @@ func (r *VisualizationRepo) Update(ctx context.Context, visualizationID uuid.UUI
 		argId++
 	}
 
-	// if dto.ViewCount != nil {
-	// 	setValues = append(setValues, fmt.Sprintf("view_count=$%d", argId))
-	// 	args = append(args, *dto.ViewCount)
-	// 	argId++
-	// }
-
-	setValues = append(setValues, fmt.Sprintf("is_saved=$%d", argId))
-	args = append(args, true)
-	argId++
-
-	setValues = append(setValues, fmt.Sprintf("is_publishable=$%d", argId))
-	args = append(args, true)
-	argId++
+	// Editing content invalidates an approval; a published snapshot stays
+	// live until the new draft is published again.
+	if len(setValues) > 0 {
+		setValues = append(setValues, "status=CASE WHEN status IN ('approved', 'published') THEN 'draft' ELSE status END")
+	}
 
 	setValues = append(setValues, "updated_at=NOW()")
 
 	setQuery := strings.Join(setValues, ", ")
 
-	q := fmt.Sprintf("UPDATE visualizations SET %s WHERE id=$%d", setQuery, argId)
-	args = append(args, visualizationID)
+	q := fmt.Sprintf("UPDATE visualizations SET %s WHERE id=$%d AND org_id=$%d AND status NOT IN ('in_review', 'archived')", setQuery, argId, argId+1)
+	args = append(args, visualizationID, orgID)
 
-	if _, err := r.db.ExecContext(ctx, q, args...); err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		return fmt.Errorf("%w", ErrFailedToUpdateVisualization)
 	}
 
-	return nil
+	// No row matched either because the visualization is gone or because it
+	// went into review or the archive after the caller checked its status.
+	return r.transitioned(ctx, op, res, visualizationID, orgID)
+}
+
+// Publish freezes the current content of an approved visualization as the
+// snapshot served to viewers and makes it live.
+func (r *VisualizationRepo) Publish(ctx context.Context, visualizationID, publishedBy uuid.UUID) error {
+	const op = "repository.VisualizationRepo.Publish"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Publishing by hand supersedes a pending scheduled publish.
+	query := fmt.Sprintf(`
+  UPDATE visualizations
+  SET status = 'published',
+      is_published = TRUE,
+      published_at = NOW(),
+      published_by = $3,
+      published_snapshot = %s,
+      publish_at = NULL
+  WHERE id = $1 AND org_id = $2 AND status = 'approved'
+  `, publishedSnapshot)
+
+	res, err := r.db.ExecContext(ctx, query, visualizationID, orgID, publishedBy)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateVisualization)
+	}
+
+	return r.transitioned(ctx, op, res, visualizationID, orgID)
 }
 
-func (r *VisualizationRepo) IncrementViewCount(ctx context.Context, visualizationID uuid.UUID) error {
-	const op = "repository.VisualizationRepo.IncrementViewCount"
+// Transition moves a visualization from one of the states in from to state
+// to. Archiving also takes the published snapshot offline and drops any
+// pending schedule.
+func (r *VisualizationRepo) Transition(ctx context.Context, visualizationID uuid.UUID, from []string, to string) error {
+	const op = "repository.VisualizationRepo.Transition"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
-	if _, err := r.db.ExecContext(ctx, "UPDATE visualizations SET view_count = view_count + 1, viewed_at=NOW() WHERE id = $1", visualizationID); err != nil {
-		r.log.Error(fmt.Sprintf("%s: %s", op, err))
-		return fmt.Errorf("%w", ErrFailedToIncrementViewCountVisualization)
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+  UPDATE visualizations
+  SET status = $3,
+      is_published = CASE WHEN $3 = 'archived' THEN FALSE ELSE is_published END,
+      publish_at = CASE WHEN $3 = 'archived' THEN NULL ELSE publish_at END,
+      unpublish_at = CASE WHEN $3 = 'archived' THEN NULL ELSE unpublish_at END
+  WHERE id = $1 AND org_id = $2 AND status = ANY($4)
+  `
+
+	res, err := r.db.ExecContext(ctx, query, visualizationID, orgID, to, pq.StringArray(from))
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateVisualization)
+	}
+
+	return r.transitioned(ctx, op, res, visualizationID, orgID)
+}
+
+// Schedule replaces the publish and unpublish times of a visualization.
+// Archived visualizations cannot be scheduled.
+func (r *VisualizationRepo) Schedule(ctx context.Context, visualizationID uuid.UUID, publishAt, unpublishAt *time.Time, scheduledBy uuid.UUID) error {
+	const op = "repository.VisualizationRepo.Schedule"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	orgID, err := activeOrgID(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+  UPDATE visualizations
+  SET publish_at = $3, unpublish_at = $4, scheduled_by = $5
+  WHERE id = $1 AND org_id = $2 AND status <> 'archived'
+  `
+
+	res, err := r.db.ExecContext(ctx, query, visualizationID, orgID, publishAt, unpublishAt, scheduledBy)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateVisualization)
+	}
+
+	return r.transitioned(ctx, op, res, visualizationID, orgID)
+}
+
+// PublishDue publishes up to limit approved visualizations whose publish time
+// has passed. It runs without an organization scope on behalf of the
+// scheduler; SKIP LOCKED lets several instances poll concurrently without
+// applying the same row twice.
+func (r *VisualizationRepo) PublishDue(ctx context.Context, limit int) ([]models.ScheduledTransition, error) {
+	const op = "repository.VisualizationRepo.PublishDue"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := fmt.Sprintf(`
+  WITH due AS (
+    SELECT id, publish_at AS due_at
+    FROM visualizations
+    WHERE publish_at <= NOW() AND status = 'approved'
+    ORDER BY publish_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+  )
+  UPDATE visualizations v
+  SET status = 'published',
+      is_published = TRUE,
+      published_at = NOW(),
+      published_by = v.scheduled_by,
+      published_snapshot = %s,
+      publish_at = NULL
+  FROM due
+  WHERE v.id = due.id
+  RETURNING v.id, v.org_id, v.status, v.scheduled_by, due.due_at
+  `, publishedSnapshot)
+
+	transitions := make([]models.ScheduledTransition, 0)
+	if err := r.db.SelectContext(ctx, &transitions, query, limit); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrFailedToUpdateVisualization)
+	}
+
+	return transitions, nil
+}
+
+// UnpublishDue takes up to limit live visualizations offline once their
+// unpublish time has passed. A published visualization is archived; one whose
+// working copy has moved on keeps its workflow state and only loses the live
+// snapshot.
+func (r *VisualizationRepo) UnpublishDue(ctx context.Context, limit int) ([]models.ScheduledTransition, error) {
+	const op = "repository.VisualizationRepo.UnpublishDue"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query := `
+  WITH due AS (
+    SELECT id, unpublish_at AS due_at
+    FROM visualizations
+    WHERE unpublish_at <= NOW() AND is_published
+    ORDER BY unpublish_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+  )
+  UPDATE visualizations v
+  SET is_published = FALSE,
+      status = CASE WHEN v.status = 'published' THEN 'archived' ELSE v.status END,
+      unpublish_at = NULL
+  FROM due
+  WHERE v.id = due.id
+  RETURNING v.id, v.org_id, v.status, v.scheduled_by, due.due_at
+  `
+
+	transitions := make([]models.ScheduledTransition, 0)
+	if err := r.db.SelectContext(ctx, &transitions, query, limit); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrFailedToUpdateVisualization)
+	}
+
+	return transitions, nil
+}
+
+// transitioned tells a missing visualization apart from one that exists but
+// was not in a state the transition starts from.
+func (r *VisualizationRepo) transitioned(ctx context.Context, op string, res sql.Result, visualizationID, orgID uuid.UUID) error {
+	if err := checkAffected(res, ErrInvalidTransition); !errors.Is(err, ErrInvalidTransition) {
+		return err
+	}
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM visualizations WHERE id = $1 AND org_id = $2)", visualizationID, orgID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToUpdateVisualization)
+	}
+	if !exists {
+		return fmt.Errorf("%s: %w", op, ErrVisualizationNotFound)
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrInvalidTransition)
+}
+
+// AddViewCounts adds buffered view counts in one statement. It is called on
+// behalf of public pages and therefore not scoped to an organization.
+func (r *VisualizationRepo) AddViewCounts(ctx context.Context, counts map[uuid.UUID]int) error {
+	const op = "repository.VisualizationRepo.AddViewCounts"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	ids, ns := viewCountArrays(counts)
+
+	// Rows are locked in ID order so concurrent flushes from several
+	// instances cannot deadlock.
+	query := `
+  WITH c AS (SELECT * FROM unnest($1::uuid[], $2::bigint[]) AS c(id, n)),
+  locked AS (SELECT v.id FROM visualizations v JOIN c ON c.id = v.id ORDER BY v.id FOR UPDATE)
+  UPDATE visualizations v SET view_count = v.view_count + c.n, viewed_at = NOW()
+  FROM c
+  WHERE v.id = c.id AND v.id IN (SELECT id FROM locked)
+  `
+
+	if _, err := r.db.ExecContext(ctx, query, ids, ns); err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, ErrFailedToIncrementViewCountVisualization)
 	}
 
 	return nil
@@ -260,12 +602,21 @@ func (r *VisualizationRepo) IncrementViewCount(ctx context.Context, visualizatio
 
 func (r *VisualizationRepo) Delete(ctx context.Context, visualizationID uuid.UUID) error {
 	const op = "repository.VisualizationRepo.Delete"
+	defer metrics.ObserveQuery(op, time.Now())
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 
-	_, err := r.db.ExecContext(ctx, "DELETE FROM visualizations WHERE id = $1", visualizationID)
+	orgID, err := activeOrgID(ctx)
 	if err != nil {
-		r.log.Error(fmt.Sprintf("%s: %v", op, err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM visualizations WHERE id = $1 AND org_id = $2", visualizationID, orgID)
+	if err != nil {
+		logger.FromContext(ctx, r.log).Error("query failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
 		return fmt.Errorf("failed to delete visualization")
 	}
 
-	return nil
+	return checkAffected(res, fmt.Errorf("%w", ErrVisualizationNotFound))
 }
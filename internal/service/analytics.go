@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/ratelimit"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/lib/useragent"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// defaultAnalyticsRange is used when the query has no lower bound.
+const defaultAnalyticsRange = 30 * 24 * time.Hour
+
+var (
+	ErrAnalyticsRangeInvalid  = errors.New("analytics range must end after it starts")
+	ErrAnalyticsRangeTooLarge = errors.New("analytics range has too many buckets for the interval")
+)
+
+var bucketLengths = map[string]time.Duration{
+	"hour":  time.Hour,
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// Viewer describes the client behind a view. Only a keyed hash of the
+// client's network, the user agent class and the referring host are stored.
+type Viewer struct {
+	IP        string
+	UserAgent string
+	Referrer  string
+}
+
+type AnalyticsService struct {
+	log            *slog.Logger
+	repo           repository.View
+	visualizations repository.Visualization
+	counter        *ViewCounter
+	trackByIP      *ratelimit.Limiter
+	cfg            config.Analytics
+}
+
+func NewAnalyticsService(log *slog.Logger, repo repository.View, visualizations repository.Visualization, counter *ViewCounter, cfg config.Analytics) *AnalyticsService {
+	return &AnalyticsService{
+		log:            log,
+		repo:           repo,
+		visualizations: visualizations,
+		counter:        counter,
+		trackByIP:      ratelimit.New(cfg.TrackPerIP, cfg.TrackWindow),
+		cfg:            cfg,
+	}
+}
+
+// RecordView stores a view of a visualization and bumps the view counts of
+// the visualization and share link. Bots and repeated views by the same
+// viewer within the dedup window are dropped; the result reports whether the
+// view was counted.
+func (as *AnalyticsService) RecordView(ctx context.Context, visualizationID uuid.UUID, shareLinkID *uuid.UUID, viewer Viewer) (bool, error) {
+	const op = "service.AnalyticsService.RecordView"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	class := useragent.Classify(viewer.UserAgent)
+	if class == useragent.ClassBot {
+		return false, nil
+	}
+
+	counted, err := as.repo.Record(ctx, models.VisualizationView{
+		VisualizationID: visualizationID,
+		ShareLinkID:     shareLinkID,
+		ViewerHash:      as.viewerHash(visualizationID, viewer),
+		Referrer:        referrerHost(viewer.Referrer),
+		UserAgentClass:  class,
+	}, as.cfg.DedupWindow)
+	if err != nil || !counted {
+		return false, err
+	}
+
+	as.counter.Add(visualizationID, shareLinkID)
+
+	return true, nil
+}
+
+// TrackView records a view reported by a page that rendered a published
+// visualization on its own. Anyone may report views, so each network is
+// throttled.
+func (as *AnalyticsService) TrackView(ctx context.Context, visualizationID uuid.UUID, viewer Viewer) error {
+	const op = "service.AnalyticsService.TrackView"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if ok, retryAfter := as.trackByIP.Allow(viewerNetwork(viewer.IP)); !ok {
+		return fmt.Errorf("%s: %w", op, &RateLimitError{RetryAfter: retryAfter})
+	}
+
+	if _, err := as.visualizations.GetPublishedByID(ctx, visualizationID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := as.RecordView(ctx, visualizationID, nil, viewer); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Get returns views and unique viewers per bucket plus the top referrers of a
+// visualization of the active organization. The range defaults to the last
+// 30 days.
+func (as *AnalyticsService) Get(ctx context.Context, visualizationID uuid.UUID, query dto.AnalyticsQuery) (models.ViewAnalytics, error) {
+	const op = "service.AnalyticsService.Get"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	to := time.Now()
+	if query.To != nil {
+		to = *query.To
+	}
+	from := to.Add(-defaultAnalyticsRange)
+	if query.From != nil {
+		from = *query.From
+	}
+
+	if !to.After(from) {
+		return models.ViewAnalytics{}, fmt.Errorf("%s: %w", op, ErrAnalyticsRangeInvalid)
+	}
+	if to.Sub(from)/bucketLengths[query.Interval] >= time.Duration(as.cfg.MaxBuckets) {
+		return models.ViewAnalytics{}, fmt.Errorf("%s: %w", op, ErrAnalyticsRangeTooLarge)
+	}
+
+	if _, err := as.visualizations.GetByID(ctx, visualizationID); err != nil {
+		return models.ViewAnalytics{}, err
+	}
+
+	return as.repo.GetAnalytics(ctx, visualizationID, from, to, query.Interval)
+}
+
+// viewerHash identifies a viewer without storing the IP. The visualization
+// ID is part of the input so viewers cannot be followed across dashboards.
+// Only the network is hashed: the User-Agent is chosen by the caller, so
+// mixing it in would let one client count a view per header it sends.
+func (as *AnalyticsService) viewerHash(visualizationID uuid.UUID, viewer Viewer) string {
+	mac := hmac.New(sha256.New, []byte(as.cfg.ViewerHashKey))
+	mac.Write(visualizationID[:])
+	mac.Write([]byte(viewerNetwork(viewer.IP)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// viewerNetwork returns an IPv4 address as is and the /64 of an IPv6 one,
+// since a single IPv6 host can usually pick any address in its /64.
+func viewerNetwork(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	if addr.Is4() {
+		return addr.String()
+	}
+	return netip.PrefixFrom(addr.WithZone(""), 64).Masked().String()
+}
+
+// referrerHost keeps only the host of a referrer; paths and query strings
+// may carry personal data.
+func referrerHost(referrer string) *string {
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+	host := u.Hostname()
+	if len(host) > 255 {
+		host = host[:255]
+	}
+	return &host
+}
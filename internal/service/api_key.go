@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/securetoken"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix marks bearer tokens that are API keys rather than session JWTs.
+const APIKeyPrefix = "vk_"
+
+// apiKeyDisplayLength is how much of the key is kept in clear text so users
+// can tell their keys apart.
+const apiKeyDisplayLength = 10
+
+var (
+	ErrAPIKeyExpiryInPast  = errors.New("API key expiry must be in the future")
+	ErrAPIKeyExpiryTooLate = errors.New("API key lifetime exceeds the allowed maximum")
+	ErrAPIKeyInvalid       = errors.New("API key is invalid, expired or revoked")
+)
+
+type APIKeyService struct {
+	log   *slog.Logger
+	repo  repository.APIKey
+	audit *AuditService
+	cfg   config.APIKeys
+}
+
+func NewAPIKeyService(log *slog.Logger, repo repository.APIKey, audit *AuditService, cfg config.APIKeys) *APIKeyService {
+	return &APIKeyService{
+		log:   log,
+		repo:  repo,
+		audit: audit,
+		cfg:   cfg,
+	}
+}
+
+// Create issues a new key for the current user in the active organization.
+// The returned secret is never stored and cannot be shown again. Keys without
+// an expiry get the configured default lifetime.
+func (aks *APIKeyService) Create(ctx context.Context, dto dto.APIKeyCreateDto) (models.APIKey, string, error) {
+	const op = "service.APIKeyService.Create"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.OrgID == uuid.Nil {
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+
+	now := time.Now()
+	if dto.ExpiresAt == nil {
+		expiresAt := now.Add(aks.cfg.DefaultTTL)
+		dto.ExpiresAt = &expiresAt
+	}
+	if !dto.ExpiresAt.After(now) {
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, ErrAPIKeyExpiryInPast)
+	}
+	if dto.ExpiresAt.After(now.Add(aks.cfg.MaxTTL)) {
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, ErrAPIKeyExpiryTooLate)
+	}
+
+	slices.Sort(dto.Scopes)
+	dto.Scopes = slices.Compact(dto.Scopes)
+
+	token, _, err := securetoken.Generate()
+	if err != nil {
+		logger.FromContext(ctx, aks.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, repository.ErrFailedToCreateAPIKey)
+	}
+	secret := APIKeyPrefix + token
+
+	key, err := aks.repo.Create(ctx, identity.UserID, identity.OrgID, dto, secret[:apiKeyDisplayLength], securetoken.Hash(secret))
+	if err != nil {
+		return models.APIKey{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	aks.audit.Record(ctx, AuditEvent{
+		Action:     "apikey.created",
+		EntityType: EntityAPIKey,
+		EntityID:   key.ID.String(),
+		After:      map[string]any{"name": key.Name, "prefix": key.Prefix, "scopes": key.Scopes, "expiresAt": key.ExpiresAt},
+	})
+
+	return key, secret, nil
+}
+
+func (aks *APIKeyService) GetAll(ctx context.Context) ([]models.APIKey, error) {
+	const op = "service.APIKeyService.GetAll"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+	}
+
+	return aks.repo.GetAllForUser(ctx, identity.UserID)
+}
+
+func (aks *APIKeyService) Revoke(ctx context.Context, keyID uuid.UUID) error {
+	const op = "service.APIKeyService.Revoke"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+	}
+
+	if err := aks.repo.Revoke(ctx, identity.UserID, keyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	aks.audit.Record(ctx, AuditEvent{
+		Action:     "apikey.revoked",
+		EntityType: EntityAPIKey,
+		EntityID:   keyID.String(),
+	})
+
+	return nil
+}
+
+// resolve looks up an active key by its secret and records its use.
+func (aks *APIKeyService) resolve(ctx context.Context, secret string) (models.APIKey, error) {
+	key, err := aks.repo.GetByHash(ctx, securetoken.Hash(secret))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return models.APIKey{}, ErrAPIKeyInvalid
+		}
+		return models.APIKey{}, err
+	}
+
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return models.APIKey{}, ErrAPIKeyInvalid
+	}
+
+	if err = aks.repo.Touch(ctx, key.ID); err != nil {
+		logger.FromContext(ctx, aks.log).Error("operation failed", logger.Op("service.APIKeyService.resolve"), logger.Err(err))
+	}
+
+	return key, nil
+}
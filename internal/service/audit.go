@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/clientip"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/requestid"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Entity types recorded in the audit log.
+const (
+	EntityAPIKey        = "api_key"
+	EntityInvitation    = "invitation"
+	EntityMember        = "member"
+	EntityOrganization  = "organization"
+	EntityShareLink     = "share_link"
+	EntityTemplate      = "template"
+	EntityUser          = "user"
+	EntityVisualization = "visualization"
+)
+
+var ErrAuditForbidden = errors.New("only admins can read the audit log")
+
+// AuditEvent describes a single change. Actor and organization default to
+// the authenticated identity; ActorID and ActorName override them for events
+// that happen before authentication, such as logins.
+type AuditEvent struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     any
+	After      any
+	ActorID    uuid.UUID
+	ActorName  string
+	OrgID      uuid.UUID
+}
+
+type AuditService struct {
+	log  *slog.Logger
+	repo repository.Audit
+}
+
+func NewAuditService(log *slog.Logger, repo repository.Audit) *AuditService {
+	return &AuditService{
+		log:  log,
+		repo: repo,
+	}
+}
+
+// Record appends ev to the audit log together with the caller's IP and
+// request ID. It never fails the surrounding operation; when the entry cannot
+// be stored it is written to the application log instead.
+func (as *AuditService) Record(ctx context.Context, ev AuditEvent) {
+	const op = "service.AuditService.Record"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	entry := models.AuditEntry{
+		Action:     ev.Action,
+		EntityType: ev.EntityType,
+		EntityID:   nonEmpty(ev.EntityID),
+		Before:     auditJSON(ev.Before),
+		After:      auditJSON(ev.After),
+		IP:         nonEmpty(clientip.FromContext(ctx)),
+		RequestID:  nonEmpty(requestid.FromContext(ctx)),
+	}
+
+	identity, _ := auth.FromContext(ctx)
+
+	actorID, actorName := identity.UserID, identity.Username
+	if ev.ActorID != uuid.Nil || ev.ActorName != "" {
+		actorID, actorName = ev.ActorID, ev.ActorName
+	}
+	if actorID != uuid.Nil {
+		entry.ActorID = &actorID
+	}
+	entry.ActorName = nonEmpty(actorName)
+
+	orgID := identity.OrgID
+	if ev.OrgID != uuid.Nil {
+		orgID = ev.OrgID
+	}
+	if orgID != uuid.Nil {
+		entry.OrgID = &orgID
+	}
+
+	// Detach from request cancellation: a client hanging up must not lose
+	// the record of a change that already happened.
+	if err := as.repo.Create(context.WithoutCancel(ctx), entry); err != nil {
+		logger.FromContext(ctx, as.log).Error("failed to record audit entry", logger.Op(op), logger.Err(err),
+			slog.String("action", ev.Action), slog.String("entityType", ev.EntityType),
+			slog.String("entityId", ev.EntityID), slog.String("actor", actorName))
+	}
+}
+
+// GetAll returns a page of the audit log. Global admins see every entry,
+// organization admins only those of their active organization.
+func (as *AuditService) GetAll(ctx context.Context, query dto.AuditQuery) ([]models.AuditEntry, int, error) {
+	const op = "service.AuditService.GetAll"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query, err := as.scope(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return as.repo.GetAll(ctx, query)
+}
+
+// Export streams every entry matching query to fn, scoped like GetAll.
+func (as *AuditService) Export(ctx context.Context, query dto.AuditQuery, fn func(models.AuditEntry) error) error {
+	const op = "service.AuditService.Export"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	query, err := as.scope(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return as.repo.Export(ctx, query, fn)
+}
+
+func (as *AuditService) scope(ctx context.Context, query dto.AuditQuery) (dto.AuditQuery, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return query, ErrAuditForbidden
+	}
+
+	if identity.MissingMFA() {
+		return query, ErrAuditForbidden
+	}
+
+	if identity.Role == models.RoleAdmin {
+		return query, nil
+	}
+
+	if identity.OrgID == uuid.Nil || identity.OrgRole != models.RoleAdmin {
+		return query, ErrAuditForbidden
+	}
+
+	query.OrgID = identity.OrgID.String()
+
+	return query, nil
+}
+
+func auditJSON(v any) *types.JSONText {
+	if v == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	text := types.JSONText(raw)
+	return &text
+}
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmbedTTLTooLong      = errors.New("embed token lifetime exceeds the allowed maximum")
+	ErrEmbedOriginInvalid   = errors.New("allowed origins must be http(s) origins without a path")
+	ErrEmbedNotPublished    = errors.New("only published visualizations can be embedded")
+	ErrEmbedTokenInvalid    = errors.New("embed token is invalid or has expired")
+	ErrEmbedTokenNotAllowed = errors.New("embed tokens can only be issued for the active organization")
+)
+
+// EmbedToken is a freshly minted embed token.
+type EmbedToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// EmbedView is what an embedding page receives: the visualization plus the
+// parameter values it must apply and not let the viewer change.
+type EmbedView struct {
+	Visualization  models.PublicVisualization `json:"visualization"`
+	Params         map[string]string          `json:"params"`
+	AllowedOrigins []string                   `json:"allowedOrigins"`
+	ExpiresAt      time.Time                  `json:"expiresAt"`
+}
+
+type EmbedService struct {
+	log            *slog.Logger
+	visualizations repository.Visualization
+	audit          *AuditService
+	analytics      *AnalyticsService
+	tokens         *auth.TokenManager
+	cfg            config.Embed
+}
+
+func NewEmbedService(log *slog.Logger, visualizations repository.Visualization, audit *AuditService, analytics *AnalyticsService, tokens *auth.TokenManager, cfg config.Embed) *EmbedService {
+	return &EmbedService{
+		log:            log,
+		visualizations: visualizations,
+		audit:          audit,
+		analytics:      analytics,
+		tokens:         tokens,
+		cfg:            cfg,
+	}
+}
+
+// Issue mints a signed embed token for a published visualization of the
+// active organization. Embedding exposes the visualization outside the
+// application, so API keys need the publish scope.
+func (es *EmbedService) Issue(ctx context.Context, visualizationID uuid.UUID, dto dto.EmbedTokenCreateDto) (EmbedToken, error) {
+	const op = "service.EmbedService.Issue"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.OrgID == uuid.Nil {
+		return EmbedToken{}, fmt.Errorf("%s: %w", op, ErrEmbedTokenNotAllowed)
+	}
+	if !identity.HasScope(auth.ScopePublish) {
+		return EmbedToken{}, fmt.Errorf("%s: %w", op, auth.ErrInsufficientScope)
+	}
+
+	ttl := es.cfg.DefaultTTL
+	if dto.ExpiresIn > 0 {
+		ttl = time.Duration(dto.ExpiresIn) * time.Second
+	}
+	if ttl > es.cfg.MaxTTL {
+		return EmbedToken{}, fmt.Errorf("%s: %w", op, ErrEmbedTTLTooLong)
+	}
+
+	origins, err := normalizeOrigins(dto.AllowedOrigins)
+	if err != nil {
+		return EmbedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	visualization, err := es.visualizations.GetByID(ctx, visualizationID)
+	if err != nil {
+		return EmbedToken{}, err
+	}
+	if !visualization.IsPublished {
+		return EmbedToken{}, fmt.Errorf("%s: %w", op, ErrEmbedNotPublished)
+	}
+
+	token, expiresAt, err := es.tokens.IssueEmbed(auth.Embed{
+		VisualizationID: visualization.ID,
+		OrgID:           visualization.OrgID,
+		Params:          dto.Params,
+		Origins:         origins,
+	}, ttl)
+	if err != nil {
+		logger.FromContext(ctx, es.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return EmbedToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	es.audit.Record(ctx, AuditEvent{
+		Action:     "embed.token_issued",
+		EntityType: EntityVisualization,
+		EntityID:   visualization.ID.String(),
+		After: map[string]any{
+			"expiresAt":      expiresAt,
+			"params":         dto.Params,
+			"allowedOrigins": origins,
+		},
+	})
+
+	return EmbedToken{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// Open validates an embed token and returns the visualization it grants
+// access to. Unpublishing a visualization invalidates all of its tokens.
+func (es *EmbedService) Open(ctx context.Context, token string, viewer Viewer) (EmbedView, error) {
+	const op = "service.EmbedService.Open"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	embed, err := es.tokens.ParseEmbed(token)
+	if err != nil {
+		return EmbedView{}, fmt.Errorf("%s: %w: %w", op, ErrEmbedTokenInvalid, err)
+	}
+
+	visualization, err := es.visualizations.GetPublishedByID(ctx, embed.VisualizationID)
+	if err != nil {
+		return EmbedView{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if visualization.OrgID != embed.OrgID {
+		return EmbedView{}, fmt.Errorf("%s: %w", op, ErrEmbedTokenInvalid)
+	}
+
+	if _, err = es.analytics.RecordView(ctx, visualization.ID, nil, viewer); err != nil {
+		logger.FromContext(ctx, es.log).Error("operation failed", logger.Op(op), logger.Err(err))
+	}
+
+	params := embed.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	return EmbedView{
+		Visualization:  publicVisualization(visualization),
+		Params:         params,
+		AllowedOrigins: embed.Origins,
+		ExpiresAt:      embed.ExpiresAt,
+	}, nil
+}
+
+// normalizeOrigins reduces each allowed origin to scheme://host[:port] so it
+// can be placed in a Content-Security-Policy header verbatim.
+func normalizeOrigins(origins []string) ([]string, error) {
+	normalized := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return nil, ErrEmbedOriginInvalid
+		}
+		normalized = append(normalized, u.Scheme+"://"+u.Host)
+	}
+
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
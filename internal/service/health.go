@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+	"visualizer-go/migrations"
+)
+
+var (
+	ErrShuttingDown       = errors.New("shutting down")
+	ErrSchemaDirty        = errors.New("last migration failed")
+	ErrSchemaOutdated     = errors.New("migrations not applied")
+	ErrWorkerNotRunning   = errors.New("not running")
+	ErrHealthCheckTimeout = errors.New("timed out")
+)
+
+// worker is a background loop the instance is not ready without.
+type worker interface {
+	Running() bool
+}
+
+type HealthService struct {
+	log           *slog.Logger
+	repo          repository.Health
+	workers       map[string]worker
+	schemaVersion uint
+	cfg           config.Health
+
+	draining atomic.Bool
+}
+
+func NewHealthService(log *slog.Logger, repo repository.Health, workers map[string]worker, cfg config.Health) *HealthService {
+	return &HealthService{
+		log:           log,
+		repo:          repo,
+		workers:       workers,
+		schemaVersion: migrations.Latest(),
+		cfg:           cfg,
+	}
+}
+
+// Drain makes readiness fail from now on, so load balancers stop routing
+// traffic to an instance that is shutting down.
+func (hs *HealthService) Drain() {
+	hs.draining.Store(true)
+}
+
+// Ready runs every readiness check concurrently, each bounded by the
+// configured timeout. The instance is ready only if all of them pass.
+func (hs *HealthService) Ready(ctx context.Context) models.HealthReport {
+	const op = "service.HealthService.Ready"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if hs.draining.Load() {
+		return models.HealthReport{
+			Status: models.HealthStatusFailing,
+			Checks: map[string]models.HealthCheck{
+				"shutdown": {Status: models.HealthStatusFailing, Error: ErrShuttingDown.Error()},
+			},
+		}
+	}
+
+	checks := map[string]func(context.Context) error{
+		"database":   hs.repo.Ping,
+		"migrations": hs.checkSchema,
+	}
+	for name, w := range hs.workers {
+		checks[name] = func(context.Context) error {
+			if !w.Running() {
+				return ErrWorkerNotRunning
+			}
+			return nil
+		}
+	}
+
+	report := models.HealthReport{
+		Status: models.HealthStatusOK,
+		Checks: make(map[string]models.HealthCheck, len(checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := hs.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != models.HealthStatusOK {
+				report.Status = models.HealthStatusFailing
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (hs *HealthService) run(ctx context.Context, check func(context.Context) error) models.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, hs.cfg.CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := models.HealthCheck{
+		Status:    models.HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Status = models.HealthStatusFailing
+		result.Error = ErrHealthCheckTimeout.Error()
+	default:
+		// Report the sentinel without the op prefix of the wrapping error.
+		if inner := errors.Unwrap(err); inner != nil {
+			err = inner
+		}
+		result.Status = models.HealthStatusFailing
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// checkSchema compares the applied migration with the newest one this
+// binary was built with.
+func (hs *HealthService) checkSchema(ctx context.Context) error {
+	const op = "service.HealthService.checkSchema"
+
+	version, dirty, err := hs.repo.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%s: %w", op, ErrSchemaDirty)
+	}
+	if version < hs.schemaVersion {
+		return fmt.Errorf("%s: %w", op, ErrSchemaOutdated)
+	}
+
+	return nil
+}
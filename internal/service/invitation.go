@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/mailer"
+	"visualizer-go/internal/lib/password"
+	"visualizer-go/internal/lib/securetoken"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var ErrFailedToSendEmail = errors.New("failed to send email")
+
+// InvitationAcceptance is the outcome of redeeming an invitation. New
+// accounts get a session Token right away; ExistingAccount users joined the
+// organization and sign in as usual.
+type InvitationAcceptance struct {
+	Token           string
+	ExistingAccount bool
+}
+
+type InvitationService struct {
+	log    *slog.Logger
+	repo   repository.Invitation
+	audit  *AuditService
+	mailer mailer.Mailer
+	tokens *auth.TokenManager
+	origin string
+	ttl    time.Duration
+}
+
+func NewInvitationService(log *slog.Logger, repo repository.Invitation, audit *AuditService, mailer mailer.Mailer, tokens *auth.TokenManager, origin string, ttl time.Duration) *InvitationService {
+	return &InvitationService{
+		log:    log,
+		repo:   repo,
+		audit:  audit,
+		mailer: mailer,
+		tokens: tokens,
+		origin: origin,
+		ttl:    ttl,
+	}
+}
+
+// Create stores a new invitation for the active organization and emails the
+// one-time token to the invitee. Only the token hash is persisted, and the
+// invitation is removed again if the email cannot be sent.
+func (is *InvitationService) Create(ctx context.Context, dto dto.InvitationCreateDto) (uuid.UUID, error) {
+	const op = "service.InvitationService.Create"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+	}
+
+	token, tokenHash, err := securetoken.Generate()
+	if err != nil {
+		logger.FromContext(ctx, is.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, repository.ErrFailedToCreateInvitation)
+	}
+
+	expiresAt := time.Now().Add(is.ttl)
+
+	invitationID, err := is.repo.Create(ctx, dto, tokenHash, identity.UserID, expiresAt)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	link := is.origin + "/invitations/accept?token=" + url.QueryEscape(token)
+
+	err = is.mailer.Send(ctx, mailer.Message{
+		To:      dto.Email,
+		Subject: "You have been invited to Visualizer",
+		Body: fmt.Sprintf("You have been invited to join Visualizer as %s.\n\n"+
+			"Follow the link below to choose a username and password:\n%s\n\n"+
+			"The invitation expires on %s.\n", dto.Role, link, expiresAt.Format(time.RFC1123)),
+	})
+	if err != nil {
+		logger.FromContext(ctx, is.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		if err = is.repo.Delete(ctx, invitationID); err != nil {
+			logger.FromContext(ctx, is.log).Error("operation failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+		}
+		return uuid.Nil, fmt.Errorf("%s: %w", op, ErrFailedToSendEmail)
+	}
+
+	is.audit.Record(ctx, AuditEvent{
+		Action:     "invitation.created",
+		EntityType: EntityInvitation,
+		EntityID:   invitationID.String(),
+		After:      map[string]any{"email": dto.Email, "role": dto.Role, "expiresAt": expiresAt},
+	})
+
+	return invitationID, nil
+}
+
+func (is *InvitationService) GetAll(ctx context.Context) ([]models.Invitation, error) {
+	const op = "service.InvitationService.GetAll"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	return is.repo.GetAll(ctx)
+}
+
+func (is *InvitationService) Revoke(ctx context.Context, invitationID uuid.UUID) error {
+	const op = "service.InvitationService.Revoke"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	if err := is.repo.Revoke(ctx, invitationID); err != nil {
+		return err
+	}
+
+	is.audit.Record(ctx, AuditEvent{
+		Action:     "invitation.revoked",
+		EntityType: EntityInvitation,
+		EntityID:   invitationID.String(),
+	})
+
+	return nil
+}
+
+// Accept redeems an invitation token. Someone who already has an account with
+// the invited email joins the organization and signs in as usual; anyone else
+// gets an account with the username and password they picked and an access
+// token for the inviting organization.
+func (is *InvitationService) Accept(ctx context.Context, dto dto.InvitationAcceptDto) (InvitationAcceptance, error) {
+	const op = "service.InvitationService.Accept"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	invitation, err := is.repo.GetByTokenHash(ctx, securetoken.Hash(dto.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return InvitationAcceptance{}, fmt.Errorf("%s: %w", op, repository.ErrInvitationInvalid)
+		}
+		return InvitationAcceptance{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var hash string
+	if dto.Password != "" {
+		if hash, err = password.Hash(dto.Password); err != nil {
+			logger.FromContext(ctx, is.log).Error("operation failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return InvitationAcceptance{}, fmt.Errorf("%s: %w", op, repository.ErrFailedToAcceptInvitation)
+		}
+	}
+
+	userID, created, err := is.repo.Accept(ctx, invitation.ID, dto.Username, hash)
+	if err != nil {
+		return InvitationAcceptance{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	actorName := dto.Username
+	if !created {
+		actorName = ""
+	}
+
+	is.audit.Record(ctx, AuditEvent{
+		Action:     "invitation.accepted",
+		EntityType: EntityInvitation,
+		EntityID:   invitation.ID.String(),
+		After:      map[string]any{"userId": userID, "role": invitation.Role, "existingAccount": !created},
+		ActorID:    userID,
+		ActorName:  actorName,
+		OrgID:      invitation.OrgID,
+	})
+
+	// Signing in existing users here would skip their second factor.
+	if !created {
+		return InvitationAcceptance{ExistingAccount: true}, nil
+	}
+
+	token, err := is.tokens.Issue(userID, models.RoleViewer, invitation.OrgID, false, 0)
+	if err != nil {
+		logger.FromContext(ctx, is.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return InvitationAcceptance{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return InvitationAcceptance{Token: token}, nil
+}
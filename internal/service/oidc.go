@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/oidc"
+	"visualizer-go/internal/lib/password"
+	"visualizer-go/internal/lib/secretbox"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// OIDCStateTTL bounds how long a user may take to sign in at the provider.
+const OIDCStateTTL = 10 * time.Minute
+
+const maxUsernameAttempts = 3
+
+var (
+	ErrSSODisabled     = errors.New("single sign-on is not configured")
+	ErrSSOStateInvalid = errors.New("single sign-on state is invalid or has expired")
+	ErrSSOFailed       = errors.New("single sign-on failed")
+)
+
+// roleRank orders roles so the most privileged mapped group wins.
+var roleRank = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleAdmin:  3,
+}
+
+// oidcState is kept encrypted in a cookie between the redirect to the
+// provider and the callback, so no server-side session store is needed.
+type oidcState struct {
+	State     string    `json:"s"`
+	Nonce     string    `json:"n"`
+	Verifier  string    `json:"v"`
+	ExpiresAt time.Time `json:"e"`
+}
+
+type OIDCService struct {
+	log      *slog.Logger
+	repo     repository.User
+	audit    *AuditService
+	sessions *UserService
+	provider *oidc.Provider
+	box      *secretbox.Box
+	cfg      config.OIDC
+	// defaultOrg is cfg.DefaultOrgID parsed, uuid.Nil when unset.
+	defaultOrg uuid.UUID
+}
+
+func NewOIDCService(log *slog.Logger, repo repository.User, audit *AuditService, sessions *UserService, box *secretbox.Box, cfg config.OIDC) *OIDCService {
+	var provider *oidc.Provider
+	if cfg.Enabled {
+		provider = oidc.New(oidc.Config{
+			Issuer:       cfg.Issuer,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+			GroupsClaim:  cfg.GroupsClaim,
+		})
+	}
+
+	// The configuration is validated at startup, so a parse error means unset.
+	defaultOrg, _ := uuid.Parse(cfg.DefaultOrgID)
+
+	return &OIDCService{
+		log:        log,
+		repo:       repo,
+		audit:      audit,
+		sessions:   sessions,
+		provider:   provider,
+		box:        box,
+		cfg:        cfg,
+		defaultOrg: defaultOrg,
+	}
+}
+
+// Begin starts the authorization code flow. It returns the provider URL to
+// redirect to and the sealed state the caller must hand back to Complete.
+func (oss *OIDCService) Begin(ctx context.Context) (string, string, error) {
+	const op = "service.OIDCService.Begin"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if oss.provider == nil {
+		return "", "", fmt.Errorf("%s: %w", op, ErrSSODisabled)
+	}
+
+	st := oidcState{ExpiresAt: time.Now().Add(OIDCStateTTL)}
+	for _, v := range []*string{&st.State, &st.Nonce, &st.Verifier} {
+		var err error
+		if *v, err = oidc.RandomString(); err != nil {
+			logger.FromContext(ctx, oss.log).Error("operation failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return "", "", fmt.Errorf("%s: %w", op, ErrSSOFailed)
+		}
+	}
+
+	raw, err := json.Marshal(st)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	sealed, err := oss.box.Seal(string(raw))
+	if err != nil {
+		logger.FromContext(ctx, oss.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", "", fmt.Errorf("%s: %w", op, ErrSSOFailed)
+	}
+
+	authURL, err := oss.provider.AuthCodeURL(ctx, st.State, st.Nonce, st.Verifier)
+	if err != nil {
+		logger.FromContext(ctx, oss.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", "", fmt.Errorf("%s: %w", op, ErrSSOFailed)
+	}
+
+	return authURL, sealed, nil
+}
+
+// Complete finishes the flow started by Begin: it redeems the code, provisions
+// or updates the user and issues a session token.
+func (oss *OIDCService) Complete(ctx context.Context, code, state, sealedState string) (string, error) {
+	const op = "service.OIDCService.Complete"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if oss.provider == nil {
+		return "", fmt.Errorf("%s: %w", op, ErrSSODisabled)
+	}
+
+	st, err := oss.openState(sealedState)
+	if err != nil || subtle.ConstantTimeCompare([]byte(st.State), []byte(state)) != 1 {
+		return "", fmt.Errorf("%s: %w", op, ErrSSOStateInvalid)
+	}
+
+	claims, err := oss.provider.Exchange(ctx, code, st.Verifier, st.Nonce)
+	if err != nil {
+		logger.FromContext(ctx, oss.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", fmt.Errorf("%s: %w", op, ErrSSOFailed)
+	}
+
+	user, err := oss.resolveUser(ctx, claims)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.IsActive {
+		return "", fmt.Errorf("%s: %w", op, ErrUserDeactivated)
+	}
+
+	// Providers report a second factor in the amr claim (RFC 8176).
+	mfa := slices.ContainsFunc(claims.AMR, func(m string) bool {
+		return m == "mfa" || m == "otp" || m == "hwk"
+	})
+
+	token, err := oss.sessions.issueSession(ctx, user, mfa)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	oss.audit.Record(ctx, AuditEvent{
+		Action:     "login.succeeded",
+		EntityType: EntityUser,
+		EntityID:   user.ID.String(),
+		After:      map[string]any{"method": "oidc", "subject": claims.Subject, "mfa": mfa},
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+	})
+
+	return token, nil
+}
+
+func (oss *OIDCService) openState(sealed string) (oidcState, error) {
+	var st oidcState
+
+	raw, err := oss.box.Open(sealed)
+	if err != nil {
+		return st, err
+	}
+	if err = json.Unmarshal([]byte(raw), &st); err != nil {
+		return st, err
+	}
+	if time.Now().After(st.ExpiresAt) {
+		return st, ErrSSOStateInvalid
+	}
+
+	return st, nil
+}
+
+// resolveUser finds the user bound to the IdP identity. Unknown identities are
+// linked to an existing account with the same verified email or provisioned
+// just in time. Roles follow the IdP groups whenever a mapping is configured.
+func (oss *OIDCService) resolveUser(ctx context.Context, claims oidc.Claims) (models.User, error) {
+	role := oss.mapRole(claims.Groups)
+
+	user, err := oss.repo.GetByOIDCSubject(ctx, oss.cfg.Issuer, claims.Subject)
+	switch {
+	case err == nil:
+	case errors.Is(err, repository.ErrUserNotFound):
+		if user, err = oss.linkByEmail(ctx, claims); errors.Is(err, repository.ErrUserNotFound) {
+			return oss.provision(ctx, claims, role)
+		}
+		if err != nil {
+			return models.User{}, err
+		}
+	default:
+		return models.User{}, err
+	}
+
+	if len(oss.cfg.GroupRoles) > 0 && user.Role != role {
+		if err = oss.repo.Update(ctx, user.ID, dto.UserUpdateDto{Role: &role}); err != nil {
+			return models.User{}, err
+		}
+
+		oss.audit.Record(ctx, AuditEvent{
+			Action:     "user.updated",
+			EntityType: EntityUser,
+			EntityID:   user.ID.String(),
+			Before:     map[string]any{"role": user.Role},
+			After:      map[string]any{"role": role, "source": "oidc"},
+			ActorID:    user.ID,
+			ActorName:  user.Username,
+		})
+
+		user.Role = role
+	}
+
+	return user, nil
+}
+
+func (oss *OIDCService) linkByEmail(ctx context.Context, claims oidc.Claims) (models.User, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return models.User{}, repository.ErrUserNotFound
+	}
+
+	user, err := oss.repo.GetByEmail(ctx, claims.Email)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if err = oss.repo.LinkOIDC(ctx, user.ID, oss.cfg.Issuer, claims.Subject); err != nil {
+		// The account is already bound to another IdP identity.
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return models.User{}, ErrSSOFailed
+		}
+		return models.User{}, err
+	}
+
+	oss.audit.Record(ctx, AuditEvent{
+		Action:     "user.sso_linked",
+		EntityType: EntityUser,
+		EntityID:   user.ID.String(),
+		After:      map[string]any{"issuer": oss.cfg.Issuer, "subject": claims.Subject},
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+	})
+
+	return user, nil
+}
+
+func (oss *OIDCService) provision(ctx context.Context, claims oidc.Claims, role string) (models.User, error) {
+	const op = "service.OIDCService.provision"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	// SSO users never sign in with a password, so store the hash of a random
+	// one instead of leaving the column empty.
+	secret, err := oidc.RandomString()
+	if err != nil {
+		return models.User{}, err
+	}
+	hash, err := password.Hash(secret)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	userDto := dto.OIDCUserDto{
+		PasswordHash: hash,
+		Role:         role,
+		Issuer:       oss.cfg.Issuer,
+		Subject:      claims.Subject,
+		OrgID:        oss.defaultOrg,
+	}
+	if claims.Email != "" && claims.EmailVerified {
+		userDto.Email = &claims.Email
+	}
+
+	base := usernameFromClaims(claims)
+	for attempt := 0; attempt < maxUsernameAttempts; attempt++ {
+		userDto.Username = base
+		if attempt > 0 {
+			userDto.Username = withRandomSuffix(base)
+		}
+
+		user, err := oss.repo.CreateOIDC(ctx, userDto)
+		if err == nil {
+			oss.audit.Record(ctx, AuditEvent{
+				Action:     "user.created",
+				EntityType: EntityUser,
+				EntityID:   user.ID.String(),
+				After:      map[string]any{"username": user.Username, "email": user.Email, "role": role, "source": "oidc"},
+				OrgID:      oss.defaultOrg,
+				ActorID:    user.ID,
+				ActorName:  user.Username,
+			})
+			return user, nil
+		}
+		if !errors.Is(err, repository.ErrUsernameOrEmailAlreadyUsed) {
+			return models.User{}, err
+		}
+	}
+
+	return models.User{}, fmt.Errorf("%s: %w", op, repository.ErrUsernameOrEmailAlreadyUsed)
+}
+
+// mapRole returns the most privileged role mapped from groups.
+func (oss *OIDCService) mapRole(groups []string) string {
+	role := oss.cfg.DefaultRole
+	for _, group := range groups {
+		if mapped, ok := oss.cfg.GroupRoles[group]; ok && roleRank[mapped] > roleRank[role] {
+			role = mapped
+		}
+	}
+	if roleRank[role] == 0 {
+		return models.RoleViewer
+	}
+	return role
+}
+
+func usernameFromClaims(claims oidc.Claims) string {
+	username := claims.PreferredUsername
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if len(username) < 3 {
+		username = "user-" + username
+	}
+	// Leave room for the suffix added on collisions.
+	if len(username) > 56 {
+		username = username[:56]
+	}
+	return username
+}
+
+func withRandomSuffix(username string) string {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		return username
+	}
+	return username + "-" + hex.EncodeToString(buf)
+}
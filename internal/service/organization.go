@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrLastOrganizationAdmin    = errors.New("organization must keep at least one admin")
+	ErrTwoFactorSessionRequired = errors.New("sign in with two-factor authentication before requiring it for admins")
+)
+
+type OrganizationService struct {
+	log    *slog.Logger
+	repo   repository.Organization
+	audit  *AuditService
+	tokens *auth.TokenManager
+}
+
+func NewOrganizationService(log *slog.Logger, repo repository.Organization, audit *AuditService, tokens *auth.TokenManager) *OrganizationService {
+	return &OrganizationService{
+		log:    log,
+		repo:   repo,
+		audit:  audit,
+		tokens: tokens,
+	}
+}
+
+func (ors *OrganizationService) GetAllForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	const op = "service.OrganizationService.GetAllForUser"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	return ors.repo.GetAllForUser(ctx, userID)
+}
+
+func (ors *OrganizationService) Create(ctx context.Context, dto dto.OrganizationCreateDto) (uuid.UUID, error) {
+	const op = "service.OrganizationService.Create"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+	}
+
+	orgID, err := ors.repo.Create(ctx, dto, identity.UserID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	ors.audit.Record(ctx, AuditEvent{
+		Action:     "organization.created",
+		EntityType: EntityOrganization,
+		EntityID:   orgID.String(),
+		After:      map[string]any{"name": dto.Name, "slug": dto.Slug},
+		OrgID:      orgID,
+	})
+
+	return orgID, nil
+}
+
+// Switch issues a new token with orgID as the active organization.
+func (ors *OrganizationService) Switch(ctx context.Context, orgID uuid.UUID) (string, error) {
+	const op = "service.OrganizationService.Switch"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+	}
+
+	if _, err := ors.repo.GetMembership(ctx, orgID, identity.UserID); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := ors.tokens.Issue(identity.UserID, identity.Role, orgID, identity.MFA, identity.SessionVersion)
+	if err != nil {
+		logger.FromContext(ctx, ors.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// Update changes the settings of the active organization. Requiring 2FA for
+// admins is only allowed from a session that passed 2FA itself, so the
+// acting admin cannot lock themselves out.
+func (ors *OrganizationService) Update(ctx context.Context, dto dto.OrganizationUpdateDto) error {
+	const op = "service.OrganizationService.Update"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.OrgID == uuid.Nil {
+		return fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+
+	if dto.RequireAdminTwoFactor != nil && *dto.RequireAdminTwoFactor && !identity.MFA {
+		return fmt.Errorf("%s: %w", op, ErrTwoFactorSessionRequired)
+	}
+
+	if err := ors.repo.Update(ctx, identity.OrgID, dto); err != nil {
+		return err
+	}
+
+	ors.audit.Record(ctx, AuditEvent{
+		Action:     "organization.updated",
+		EntityType: EntityOrganization,
+		EntityID:   identity.OrgID.String(),
+		After:      map[string]any{"requireAdminTwoFactor": dto.RequireAdminTwoFactor},
+	})
+
+	return nil
+}
+
+func (ors *OrganizationService) GetMembers(ctx context.Context) ([]models.OrganizationMember, error) {
+	const op = "service.OrganizationService.GetMembers"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.OrgID == uuid.Nil {
+		return nil, fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+
+	return ors.repo.GetMembers(ctx, identity.OrgID)
+}
+
+func (ors *OrganizationService) AddMember(ctx context.Context, dto dto.OrganizationMemberCreateDto) error {
+	const op = "service.OrganizationService.AddMember"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.OrgID == uuid.Nil {
+		return fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+
+	if err := ors.repo.AddMember(ctx, identity.OrgID, dto); err != nil {
+		return err
+	}
+
+	ors.audit.Record(ctx, AuditEvent{
+		Action:     "member.added",
+		EntityType: EntityMember,
+		EntityID:   dto.UserID.String(),
+		After:      map[string]any{"role": dto.Role},
+	})
+
+	return nil
+}
+
+func (ors *OrganizationService) UpdateMember(ctx context.Context, userID uuid.UUID, dto dto.OrganizationMemberUpdateDto) error {
+	const op = "service.OrganizationService.UpdateMember"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.OrgID == uuid.Nil {
+		return fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+
+	if dto.Role != models.RoleAdmin {
+		if err := ors.ensureAnotherAdmin(ctx, identity.OrgID, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	before, err := ors.repo.GetMembership(ctx, identity.OrgID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = ors.repo.UpdateMember(ctx, identity.OrgID, userID, dto); err != nil {
+		return err
+	}
+
+	ors.audit.Record(ctx, AuditEvent{
+		Action:     "member.updated",
+		EntityType: EntityMember,
+		EntityID:   userID.String(),
+		Before:     map[string]any{"role": before.Role},
+		After:      map[string]any{"role": dto.Role},
+	})
+
+	return nil
+}
+
+func (ors *OrganizationService) RemoveMember(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.OrganizationService.RemoveMember"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok || identity.OrgID == uuid.Nil {
+		return fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+
+	if err := ors.ensureAnotherAdmin(ctx, identity.OrgID, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	before, err := ors.repo.GetMembership(ctx, identity.OrgID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = ors.repo.RemoveMember(ctx, identity.OrgID, userID); err != nil {
+		return err
+	}
+
+	ors.audit.Record(ctx, AuditEvent{
+		Action:     "member.removed",
+		EntityType: EntityMember,
+		EntityID:   userID.String(),
+		Before:     map[string]any{"username": before.Username, "role": before.Role},
+	})
+
+	return nil
+}
+
+// ensureAnotherAdmin fails when userID is the only admin left in the organization.
+func (ors *OrganizationService) ensureAnotherAdmin(ctx context.Context, orgID, userID uuid.UUID) error {
+	members, err := ors.repo.GetMembers(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		if m.Role == models.RoleAdmin && m.UserID != userID {
+			return nil
+		}
+	}
+
+	return ErrLastOrganizationAdmin
+}
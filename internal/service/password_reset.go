@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/mailer"
+	"visualizer-go/internal/lib/password"
+	"visualizer-go/internal/lib/ratelimit"
+	"visualizer-go/internal/lib/securetoken"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/repository"
+)
+
+// RateLimitError is returned when a caller exceeded a rate limit.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many requests, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+type PasswordResetService struct {
+	log       *slog.Logger
+	repo      repository.PasswordReset
+	users     repository.User
+	audit     *AuditService
+	mailer    mailer.Mailer
+	origin    string
+	ttl       time.Duration
+	byAccount *ratelimit.Limiter
+	byIP      *ratelimit.Limiter
+}
+
+func NewPasswordResetService(log *slog.Logger, repo repository.PasswordReset, users repository.User, audit *AuditService, mailer mailer.Mailer, origin string, cfg config.PasswordReset) *PasswordResetService {
+	return &PasswordResetService{
+		log:       log,
+		repo:      repo,
+		users:     users,
+		audit:     audit,
+		mailer:    mailer,
+		origin:    origin,
+		ttl:       cfg.TTL,
+		byAccount: ratelimit.New(cfg.MaxPerAccount, cfg.Window),
+		byIP:      ratelimit.New(cfg.MaxPerIP, cfg.Window),
+	}
+}
+
+// forgotTimeout bounds the background work of a single reset request.
+const forgotTimeout = 30 * time.Second
+
+// Forgot emails a reset link to the account with the given address. Only the
+// per-IP limit is reported; everything else, including failures, happens in
+// the background, so neither the response nor its timing reveals whether an
+// address is registered, active or rate-limited.
+func (ps *PasswordResetService) Forgot(ctx context.Context, dto dto.PasswordForgotDto, ip string) error {
+	const op = "service.PasswordResetService.Forgot"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if ok, retryAfter := ps.byIP.Allow(ip); !ok {
+		return fmt.Errorf("%s: %w", op, &RateLimitError{RetryAfter: retryAfter})
+	}
+
+	// The request context ends with the response; keep its logger and trace.
+	bgCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forgotTimeout)
+	go func() {
+		defer cancel()
+		if err := ps.sendResetLink(bgCtx, dto.Email, ip); err != nil {
+			logger.FromContext(bgCtx, ps.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		}
+	}()
+
+	return nil
+}
+
+// sendResetLink creates a reset token for the active account with email and
+// mails it. Unknown, deactivated and rate-limited accounts are skipped.
+func (ps *PasswordResetService) sendResetLink(ctx context.Context, email, ip string) error {
+	const op = "service.PasswordResetService.sendResetLink"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	user, err := ps.users.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	if ok, _ := ps.byAccount.Allow(user.ID.String()); !ok {
+		logger.FromContext(ctx, ps.log).Warn("reset rate limit exceeded", logger.Op(op), slog.String("user_id", user.ID.String()))
+		return nil
+	}
+
+	token, tokenHash, err := securetoken.Generate()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = ps.repo.Create(ctx, user.ID, tokenHash, time.Now().Add(ps.ttl), ip); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ps.audit.Record(ctx, AuditEvent{
+		Action:     "password.reset_requested",
+		EntityType: EntityUser,
+		EntityID:   user.ID.String(),
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+	})
+
+	link := ps.origin + "/password/reset?token=" + url.QueryEscape(token)
+
+	err = ps.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Reset your Visualizer password",
+		Body: fmt.Sprintf("Hi %s,\n\nsomeone requested a password reset for your account. "+
+			"If it was you, follow the link below within %s:\n%s\n\n"+
+			"If you did not request a reset you can ignore this email.\n", user.Username, ps.ttl, link),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrFailedToSendEmail, err)
+	}
+
+	return nil
+}
+
+// Reset sets a new password using a reset token and signs the user out everywhere.
+func (ps *PasswordResetService) Reset(ctx context.Context, dto dto.PasswordResetDto) error {
+	const op = "service.PasswordResetService.Reset"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	hash, err := password.Hash(dto.Password)
+	if err != nil {
+		logger.FromContext(ctx, ps.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, repository.ErrFailedToResetPassword)
+	}
+
+	userID, err := ps.repo.Reset(ctx, securetoken.Hash(dto.Token), hash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	ps.audit.Record(ctx, AuditEvent{
+		Action:     "password.reset",
+		EntityType: EntityUser,
+		EntityID:   userID.String(),
+		ActorID:    userID,
+	})
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+)
+
+// schedulerActor is recorded as the actor of automatic changes.
+const schedulerActor = "scheduler"
+
+// PublishScheduler periodically applies scheduled publishes and unpublishes.
+type PublishScheduler struct {
+	log    *slog.Logger
+	repo   repository.Visualization
+	audit  *AuditService
+	shares *ShareCache
+	cfg    config.Scheduler
+
+	running atomic.Bool
+}
+
+func NewPublishScheduler(log *slog.Logger, repo repository.Visualization, audit *AuditService, shares *ShareCache, cfg config.Scheduler) *PublishScheduler {
+	return &PublishScheduler{
+		log:    log,
+		repo:   repo,
+		audit:  audit,
+		shares: shares,
+		cfg:    cfg,
+	}
+}
+
+// Run polls for due visualizations until ctx is cancelled.
+func (ps *PublishScheduler) Run(ctx context.Context) {
+	const op = "service.PublishScheduler.Run"
+
+	log := ps.log.With(slog.String("op", op))
+
+	if !ps.cfg.Enabled {
+		log.Info("publish scheduler disabled")
+		return
+	}
+
+	log.Info("starting publish scheduler...", slog.Duration("interval", ps.cfg.Interval))
+
+	ps.running.Store(true)
+	defer ps.running.Store(false)
+
+	ticker := time.NewTicker(ps.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		ps.apply(ctx, "visualization.published", ps.repo.PublishDue)
+		ps.apply(ctx, "visualization.unpublished", ps.repo.UnpublishDue)
+
+		select {
+		case <-ctx.Done():
+			log.Info("publish scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Running reports whether Run is polling.
+func (ps *PublishScheduler) Running() bool {
+	return ps.running.Load()
+}
+
+// apply drains every due batch so a backlog is not spread over many ticks.
+func (ps *PublishScheduler) apply(ctx context.Context, action string, due func(context.Context, int) ([]models.ScheduledTransition, error)) {
+	const op = "service.PublishScheduler.apply"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	for ctx.Err() == nil {
+		transitions, err := due(ctx, ps.cfg.BatchSize)
+		if err != nil {
+			ps.log.Error("failed to apply scheduled transitions", logger.Op(op), logger.Err(err), slog.String("action", action))
+			return
+		}
+
+		for _, t := range transitions {
+			ps.shares.InvalidateVisualization(t.VisualizationID)
+			ps.audit.Record(ctx, AuditEvent{
+				Action:     action,
+				EntityType: EntityVisualization,
+				EntityID:   t.VisualizationID.String(),
+				After:      map[string]any{"status": t.Status, "scheduledAt": t.DueAt, "scheduledBy": t.ScheduledBy},
+				ActorName:  schedulerActor,
+				OrgID:      t.OrgID,
+			})
+		}
+
+		if len(transitions) > 0 {
+			ps.log.Info("applied scheduled transitions", slog.String("action", action), slog.Int("count", len(transitions)))
+		}
+		if len(transitions) == 0 || len(transitions) < ps.cfg.BatchSize {
+			return
+		}
+	}
+}
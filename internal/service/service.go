@@ -3,7 +3,13 @@ package service
 import (
 	"context"
 	"log/slog"
+	"time"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/bruteforce"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/mailer"
+	"visualizer-go/internal/lib/secretbox"
 	"visualizer-go/internal/models"
 	"visualizer-go/internal/repository"
 
@@ -19,39 +25,180 @@ type (
 	}
 
 	User interface {
-		Login(ctx context.Context, dto dto.UserLoginDto) (models.User, string, error)
+		Login(ctx context.Context, dto dto.UserLoginDto, ip string) (models.User, string, error)
+		VerifyTwoFactor(ctx context.Context, dto dto.TwoFactorVerifyDto, ip string) (models.User, string, error)
+		Authenticate(ctx context.Context, token string) (auth.Identity, error)
+		GetAll(ctx context.Context, search string, limit, offset int) ([]models.User, int, error)
 		GetByID(ctx context.Context, userID uuid.UUID) (models.User, error)
 		GetByUsername(ctx context.Context, username string) (models.User, error)
 		Create(ctx context.Context, dto dto.UserCreateDto) error
 		Update(ctx context.Context, userID uuid.UUID, dto dto.UserUpdateDto) error
+		Deactivate(ctx context.Context, userID uuid.UUID) error
+		Reactivate(ctx context.Context, userID uuid.UUID) error
+		Unlock(ctx context.Context, userID uuid.UUID) error
+		Delete(ctx context.Context, userID uuid.UUID, reassignTo uuid.UUID) error
 	}
 
 	Visualization interface {
 		GetAll(ctx context.Context) ([]models.Visualization, error)
 		GetByTemplateID(ctx context.Context, templateID uuid.UUID) ([]models.Visualization, error)
 		GetByID(ctx context.Context, visualizationID uuid.UUID) (models.Visualization, error)
-		GetByShareID(ctx context.Context, shareID uuid.UUID) (models.Visualization, error)
 		Create(ctx context.Context, dto dto.VisualizationCreateDto) (uuid.UUID, error)
 		Update(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationUpdateDto) error
-		IncrementViewCount(ctx context.Context, visualizationID uuid.UUID) error
+		Submit(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationSubmitDto) error
+		Approve(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationReviewDto) (string, error)
+		Reject(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationRejectDto) (string, error)
+		GetReviews(ctx context.Context, visualizationID uuid.UUID) ([]models.VisualizationReview, error)
+		Publish(ctx context.Context, visualizationID uuid.UUID) error
+		Archive(ctx context.Context, visualizationID uuid.UUID) error
+		Restore(ctx context.Context, visualizationID uuid.UUID) error
+		Schedule(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationScheduleDto) error
 		Delete(ctx context.Context, visualizationID uuid.UUID) error
 	}
 
+	Organization interface {
+		GetAllForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error)
+		Create(ctx context.Context, dto dto.OrganizationCreateDto) (uuid.UUID, error)
+		Switch(ctx context.Context, orgID uuid.UUID) (string, error)
+		GetMembers(ctx context.Context) ([]models.OrganizationMember, error)
+		AddMember(ctx context.Context, dto dto.OrganizationMemberCreateDto) error
+		UpdateMember(ctx context.Context, userID uuid.UUID, dto dto.OrganizationMemberUpdateDto) error
+		RemoveMember(ctx context.Context, userID uuid.UUID) error
+		Update(ctx context.Context, dto dto.OrganizationUpdateDto) error
+	}
+
+	Invitation interface {
+		Create(ctx context.Context, dto dto.InvitationCreateDto) (uuid.UUID, error)
+		GetAll(ctx context.Context) ([]models.Invitation, error)
+		Revoke(ctx context.Context, invitationID uuid.UUID) error
+		Accept(ctx context.Context, dto dto.InvitationAcceptDto) (InvitationAcceptance, error)
+	}
+
+	PasswordReset interface {
+		Forgot(ctx context.Context, dto dto.PasswordForgotDto, ip string) error
+		Reset(ctx context.Context, dto dto.PasswordResetDto) error
+	}
+
+	TwoFactor interface {
+		Enroll(ctx context.Context) (TwoFactorEnrollment, error)
+		Confirm(ctx context.Context, dto dto.TwoFactorConfirmDto) ([]string, error)
+		Disable(ctx context.Context, dto dto.TwoFactorCodeDto) error
+		Reset(ctx context.Context, userID uuid.UUID) error
+	}
+
+	OIDC interface {
+		Begin(ctx context.Context) (string, string, error)
+		Complete(ctx context.Context, code, state, sealedState string) (string, error)
+	}
+
+	APIKey interface {
+		Create(ctx context.Context, dto dto.APIKeyCreateDto) (models.APIKey, string, error)
+		GetAll(ctx context.Context) ([]models.APIKey, error)
+		Revoke(ctx context.Context, keyID uuid.UUID) error
+	}
+
+	ShareLink interface {
+		GetAll(ctx context.Context, visualizationID uuid.UUID) ([]models.ShareLink, error)
+		Create(ctx context.Context, visualizationID uuid.UUID, dto dto.ShareLinkCreateDto) (models.ShareLink, error)
+		Rotate(ctx context.Context, visualizationID, linkID uuid.UUID) (uuid.UUID, error)
+		Revoke(ctx context.Context, visualizationID, linkID uuid.UUID) error
+		Open(ctx context.Context, shareID uuid.UUID, sealedViewer string, viewer Viewer) (SharedVisualization, error)
+		Unlock(ctx context.Context, shareID uuid.UUID, dto dto.ShareLinkUnlockDto, ip string) (string, error)
+	}
+
+	Embed interface {
+		Issue(ctx context.Context, visualizationID uuid.UUID, dto dto.EmbedTokenCreateDto) (EmbedToken, error)
+		Open(ctx context.Context, token string, viewer Viewer) (EmbedView, error)
+	}
+
+	Analytics interface {
+		TrackView(ctx context.Context, visualizationID uuid.UUID, viewer Viewer) error
+		Get(ctx context.Context, visualizationID uuid.UUID, query dto.AnalyticsQuery) (models.ViewAnalytics, error)
+	}
+
+	Health interface {
+		Ready(ctx context.Context) models.HealthReport
+		Drain()
+	}
+
+	Audit interface {
+		GetAll(ctx context.Context, query dto.AuditQuery) ([]models.AuditEntry, int, error)
+		Export(ctx context.Context, query dto.AuditQuery, fn func(models.AuditEntry) error) error
+	}
+
 	Deps struct {
-		Repo *repository.Repository
+		Repo          *repository.Repository
+		Tokens        *auth.TokenManager
+		Mailer        mailer.Mailer
+		Origin        string
+		InvitationTTL time.Duration
+		PasswordReset config.PasswordReset
+		LoginGuard    *bruteforce.Guard
+		SecretBox     *secretbox.Box
+		TOTPIssuer    string
+		OIDC          config.OIDC
+		APIKeys       config.APIKeys
+		Embed         config.Embed
+		Scheduler     config.Scheduler
+		Analytics     config.Analytics
+		ViewCounter   config.ViewCounter
+		Health        config.Health
+		ShareCache    config.ShareCache
 	}
 
 	Service struct {
+		APIKey
+		Analytics
+		Audit
+		Embed
+		Health
+		Invitation
+		OIDC
+		Organization
+		PasswordReset
+		ShareLink
 		Template
+		TwoFactor
 		User
 		Visualization
+
+		// Scheduler and ViewCounter run in the background and are started
+		// by the caller.
+		Scheduler   *PublishScheduler
+		ViewCounter *ViewCounter
 	}
 )
 
 func New(log *slog.Logger, deps Deps) *Service {
+	audit := NewAuditService(log, deps.Repo.Audit)
+	twoFactor := NewTwoFactorService(log, deps.Repo.TwoFactor, audit, deps.SecretBox, deps.TOTPIssuer)
+	apiKeys := NewAPIKeyService(log, deps.Repo.APIKey, audit, deps.APIKeys)
+	viewCounter := NewViewCounter(log, deps.Repo.Visualization, deps.Repo.ShareLink, deps.ViewCounter)
+	analytics := NewAnalyticsService(log, deps.Repo.View, deps.Repo.Visualization, viewCounter, deps.Analytics)
+	shareCache := NewShareCache(deps.ShareCache)
+	scheduler := NewPublishScheduler(log, deps.Repo.Visualization, audit, shareCache, deps.Scheduler)
+	workers := map[string]worker{"viewCounter": viewCounter}
+	if deps.Scheduler.Enabled {
+		workers["scheduler"] = scheduler
+	}
+	users := NewUserService(log, deps.Repo.User, deps.Repo.Organization, audit, deps.Tokens, deps.LoginGuard, twoFactor, apiKeys)
+
 	return &Service{
-		Template:      NewTemplateService(log, deps.Repo.Template),
-		User:          NewUserService(log, deps.Repo.User),
-		Visualization: NewVisualizationService(log, deps.Repo.Visualization),
+		APIKey:        apiKeys,
+		Analytics:     analytics,
+		Audit:         audit,
+		Embed:         NewEmbedService(log, deps.Repo.Visualization, audit, analytics, deps.Tokens, deps.Embed),
+		Health:        NewHealthService(log, deps.Repo.Health, workers, deps.Health),
+		Invitation:    NewInvitationService(log, deps.Repo.Invitation, audit, deps.Mailer, deps.Tokens, deps.Origin, deps.InvitationTTL),
+		OIDC:          NewOIDCService(log, deps.Repo.User, audit, users, deps.SecretBox, deps.OIDC),
+		Organization:  NewOrganizationService(log, deps.Repo.Organization, audit, deps.Tokens),
+		PasswordReset: NewPasswordResetService(log, deps.Repo.PasswordReset, deps.Repo.User, audit, deps.Mailer, deps.Origin, deps.PasswordReset),
+		ShareLink:     NewShareLinkService(log, deps.Repo.ShareLink, deps.Repo.Visualization, audit, analytics, deps.LoginGuard, deps.SecretBox, shareCache),
+		Template:      NewTemplateService(log, deps.Repo.Template, audit),
+		TwoFactor:     twoFactor,
+		User:          users,
+		Visualization: NewVisualizationService(log, deps.Repo.Visualization, deps.Repo.Review, audit, shareCache),
+		Scheduler:     scheduler,
+		ViewCounter:   viewCounter,
 	}
 }
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/lru"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SharedVisualization is a visualization opened through a share link,
+// together with what HTTP caches need to know about it.
+type SharedVisualization struct {
+	Visualization models.PublicVisualization
+	// ETag changes whenever the serialized visualization does.
+	ETag string
+	// Protected links are unlocked per viewer and must not be stored by
+	// shared caches.
+	Protected bool
+	// ExpiresAt is when the link stops working, if ever.
+	ExpiresAt *time.Time
+}
+
+type sharedEntry struct {
+	link          models.ShareLink
+	visualization models.PublicVisualization
+	etag          string
+}
+
+// ShareCache keeps the links and visualizations behind share IDs in memory,
+// so public dashboards do not query the database on every view. It only
+// knows about changes made through this instance; other instances pick them
+// up when their entries expire.
+type ShareCache struct {
+	// mu orders invalidations against adds, so an entry loaded before an
+	// invalidation is not stored after it.
+	mu         sync.Mutex
+	generation uint64
+	entries    *lru.Cache[uuid.UUID, sharedEntry]
+}
+
+func NewShareCache(cfg config.ShareCache) *ShareCache {
+	return &ShareCache{entries: lru.New[uuid.UUID, sharedEntry](cfg.Size, cfg.TTL)}
+}
+
+// InvalidateVisualization drops every share link of visualizationID.
+func (sc *ShareCache) InvalidateVisualization(visualizationID uuid.UUID) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.generation++
+	sc.entries.RemoveFunc(func(_ uuid.UUID, e sharedEntry) bool {
+		return e.visualization.ID == visualizationID
+	})
+}
+
+func (sc *ShareCache) get(shareID uuid.UUID) (sharedEntry, bool) {
+	e, ok := sc.entries.Get(shareID)
+	if ok {
+		metrics.ShareCacheLookups.WithLabelValues("hit").Inc()
+	} else {
+		metrics.ShareCacheLookups.WithLabelValues("miss").Inc()
+	}
+	return e, ok
+}
+
+// begin returns the generation to pass to add once the entry is loaded.
+func (sc *ShareCache) begin() uint64 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.generation
+}
+
+// add stores e unless something was invalidated since generation was taken.
+func (sc *ShareCache) add(shareID uuid.UUID, e sharedEntry, generation uint64) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if generation == sc.generation {
+		sc.entries.Add(shareID, e)
+	}
+}
+
+// publicVisualization keeps what a published visualization shows anonymous
+// viewers.
+func publicVisualization(v models.Visualization) models.PublicVisualization {
+	return models.PublicVisualization{
+		ID:          v.ID,
+		Name:        v.Name,
+		Description: v.Description,
+		Client:      v.Client,
+		TemplateID:  v.TemplateID,
+		Canvases:    v.Canvases,
+		Tenant:      v.Tenant,
+		IsPublished: v.IsPublished,
+		PublishedAt: v.PublishedAt,
+		UpdatedAt:   v.UpdatedAt,
+	}
+}
+
+// visualizationETag derives a strong ETag from the time the visualization
+// was published and a hash of its JSON form, which is the response body.
+// Only the snapshot and publish time are in it, so views do not change it.
+func visualizationETag(visualization models.PublicVisualization) (string, error) {
+	raw, err := json.Marshal(visualization)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+
+	return `"` + strconv.FormatInt(visualization.UpdatedAt.UnixMilli(), 36) + "-" + hex.EncodeToString(sum[:12]) + `"`, nil
+}
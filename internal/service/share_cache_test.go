@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func newTestShareEntry(visualizationID uuid.UUID) sharedEntry {
+	return sharedEntry{visualization: models.PublicVisualization{ID: visualizationID}}
+}
+
+func TestShareCacheAdd(t *testing.T) {
+	sc := NewShareCache(config.ShareCache{Size: 10, TTL: time.Minute})
+	shareID := uuid.New()
+
+	sc.add(shareID, newTestShareEntry(uuid.New()), sc.begin())
+
+	if _, ok := sc.get(shareID); !ok {
+		t.Fatal("entry loaded without a concurrent invalidation was not cached")
+	}
+}
+
+func TestShareCacheSkipsEntriesLoadedBeforeInvalidation(t *testing.T) {
+	sc := NewShareCache(config.ShareCache{Size: 10, TTL: time.Minute})
+	shareID, visualizationID := uuid.New(), uuid.New()
+
+	// A request starts loading, the visualization changes, and only then
+	// does the request try to store what it read.
+	generation := sc.begin()
+	stale := newTestShareEntry(visualizationID)
+	sc.InvalidateVisualization(visualizationID)
+	sc.add(shareID, stale, generation)
+
+	if _, ok := sc.get(shareID); ok {
+		t.Fatal("entry loaded before an invalidation was cached")
+	}
+
+	// The next load starts after the invalidation and is cached again.
+	sc.add(shareID, newTestShareEntry(visualizationID), sc.begin())
+	if _, ok := sc.get(shareID); !ok {
+		t.Fatal("entry loaded after the invalidation was not cached")
+	}
+}
+
+func TestShareCacheInvalidateVisualization(t *testing.T) {
+	sc := NewShareCache(config.ShareCache{Size: 10, TTL: time.Minute})
+	changed, other := uuid.New(), uuid.New()
+	first, second, unrelated := uuid.New(), uuid.New(), uuid.New()
+
+	sc.add(first, newTestShareEntry(changed), sc.begin())
+	sc.add(second, newTestShareEntry(changed), sc.begin())
+	sc.add(unrelated, newTestShareEntry(other), sc.begin())
+
+	sc.InvalidateVisualization(changed)
+
+	for _, shareID := range []uuid.UUID{first, second} {
+		if _, ok := sc.get(shareID); ok {
+			t.Errorf("link %s of the changed visualization is still cached", shareID)
+		}
+	}
+	if _, ok := sc.get(unrelated); !ok {
+		t.Error("link of another visualization was dropped")
+	}
+}
+
+func TestVisualizationETagIgnoresViews(t *testing.T) {
+	publishedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	visualization := models.Visualization{ID: uuid.New(), Name: "Sales", IsPublished: true, PublishedAt: &publishedAt, UpdatedAt: publishedAt}
+
+	etag, err := visualizationETag(publicVisualization(visualization))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewed := visualization
+	viewed.ViewCount = 42
+	viewed.ViewedAt = &publishedAt
+	if got, _ := visualizationETag(publicVisualization(viewed)); got != etag {
+		t.Errorf("ETag changed with the view count: %s, want %s", got, etag)
+	}
+
+	renamed := visualization
+	renamed.Name = "Revenue"
+	if got, _ := visualizationETag(publicVisualization(renamed)); got == etag {
+		t.Error("ETag did not change with the snapshot")
+	}
+}
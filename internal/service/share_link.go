@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/bruteforce"
+	"visualizer-go/internal/lib/db/replica"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/password"
+	"visualizer-go/internal/lib/secretbox"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/models"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ShareViewerTTL is how long a viewer stays unlocked after entering the
+// password of a protected share link.
+const ShareViewerTTL = time.Hour
+
+var (
+	ErrShareLinkExpired       = errors.New("share link has expired")
+	ErrShareLinkExpiryInPast  = errors.New("share link expiry must be in the future")
+	ErrSharePasswordRequired  = errors.New("share link is password protected")
+	ErrSharePasswordIncorrect = errors.New("share link password is incorrect")
+	ErrShareLinkNotProtected  = errors.New("share link is not password protected")
+)
+
+// shareViewer is kept encrypted in a cookie once a viewer has entered the
+// password of a share link. It is bound to the share ID, so rotating or
+// revoking the link invalidates it.
+type shareViewer struct {
+	LinkID    uuid.UUID `json:"l"`
+	ShareID   uuid.UUID `json:"s"`
+	ExpiresAt time.Time `json:"e"`
+}
+
+type ShareLinkService struct {
+	log            *slog.Logger
+	repo           repository.ShareLink
+	visualizations repository.Visualization
+	audit          *AuditService
+	analytics      *AnalyticsService
+	guard          *bruteforce.Guard
+	box            *secretbox.Box
+	cache          *ShareCache
+}
+
+func NewShareLinkService(log *slog.Logger, repo repository.ShareLink, visualizations repository.Visualization, audit *AuditService, analytics *AnalyticsService, guard *bruteforce.Guard, box *secretbox.Box, cache *ShareCache) *ShareLinkService {
+	return &ShareLinkService{
+		log:            log,
+		repo:           repo,
+		visualizations: visualizations,
+		audit:          audit,
+		analytics:      analytics,
+		guard:          guard,
+		box:            box,
+		cache:          cache,
+	}
+}
+
+func (ss *ShareLinkService) GetAll(ctx context.Context, visualizationID uuid.UUID) ([]models.ShareLink, error) {
+	const op = "service.ShareLinkService.GetAll"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	return ss.repo.GetAllForVisualization(ctx, visualizationID)
+}
+
+// Create adds a named share link. Like publishing, it makes a visualization
+// reachable from outside and therefore needs the publish scope.
+func (ss *ShareLinkService) Create(ctx context.Context, visualizationID uuid.UUID, dto dto.ShareLinkCreateDto) (models.ShareLink, error) {
+	const op = "service.ShareLinkService.Create"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return models.ShareLink{}, fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+	if !identity.HasScope(auth.ScopePublish) {
+		return models.ShareLink{}, fmt.Errorf("%s: %w", op, auth.ErrInsufficientScope)
+	}
+
+	if dto.ExpiresAt != nil && !dto.ExpiresAt.After(time.Now()) {
+		return models.ShareLink{}, fmt.Errorf("%s: %w", op, ErrShareLinkExpiryInPast)
+	}
+
+	var passwordHash *string
+	if dto.Password != "" {
+		hash, err := password.Hash(dto.Password)
+		if err != nil {
+			logger.FromContext(ctx, ss.log).Error("operation failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return models.ShareLink{}, fmt.Errorf("%s: %w", op, repository.ErrFailedToCreateShareLink)
+		}
+		passwordHash = &hash
+	}
+
+	link, err := ss.repo.Create(ctx, visualizationID, dto, passwordHash, identity.UserID)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+
+	ss.audit.Record(ctx, AuditEvent{
+		Action:     "sharelink.created",
+		EntityType: EntityShareLink,
+		EntityID:   link.ID.String(),
+		After: map[string]any{
+			"visualizationId": visualizationID,
+			"name":            link.Name,
+			"expiresAt":       link.ExpiresAt,
+			"hasPassword":     link.HasPassword,
+		},
+	})
+
+	return link, nil
+}
+
+// Rotate replaces the share ID of a link; the previous URL stops working.
+func (ss *ShareLinkService) Rotate(ctx context.Context, visualizationID, linkID uuid.UUID) (uuid.UUID, error) {
+	const op = "service.ShareLinkService.Rotate"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if identity, ok := auth.FromContext(ctx); ok && !identity.HasScope(auth.ScopePublish) {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, auth.ErrInsufficientScope)
+	}
+
+	shareID, err := ss.repo.Rotate(ctx, visualizationID, linkID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	ss.cache.InvalidateVisualization(visualizationID)
+
+	ss.audit.Record(ctx, AuditEvent{
+		Action:     "sharelink.rotated",
+		EntityType: EntityShareLink,
+		EntityID:   linkID.String(),
+		After:      map[string]any{"visualizationId": visualizationID},
+	})
+
+	return shareID, nil
+}
+
+func (ss *ShareLinkService) Revoke(ctx context.Context, visualizationID, linkID uuid.UUID) error {
+	const op = "service.ShareLinkService.Revoke"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if err := ss.repo.Revoke(ctx, visualizationID, linkID); err != nil {
+		return err
+	}
+
+	ss.cache.InvalidateVisualization(visualizationID)
+
+	ss.audit.Record(ctx, AuditEvent{
+		Action:     "sharelink.revoked",
+		EntityType: EntityShareLink,
+		EntityID:   linkID.String(),
+		Before:     map[string]any{"visualizationId": visualizationID},
+	})
+
+	return nil
+}
+
+// Open resolves a public share URL to its visualization and counts the view.
+// Password protected links additionally need the sealed viewer issued by
+// Unlock. Links and visualizations come from the share cache when possible;
+// expiry and the viewer are checked on every call.
+func (ss *ShareLinkService) Open(ctx context.Context, shareID uuid.UUID, sealedViewer string, viewer Viewer) (SharedVisualization, error) {
+	const op = "service.ShareLinkService.Open"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	entry, err := ss.load(ctx, shareID)
+	if err != nil {
+		return SharedVisualization{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	link := entry.link
+	if expired(link) {
+		return SharedVisualization{}, fmt.Errorf("%s: %w", op, ErrShareLinkExpired)
+	}
+
+	if link.HasPassword && !ss.validViewer(link, sealedViewer) {
+		return SharedVisualization{}, fmt.Errorf("%s: %w", op, ErrSharePasswordRequired)
+	}
+
+	// A lost view must not keep the dashboard from rendering.
+	if _, err = ss.analytics.RecordView(ctx, entry.visualization.ID, &link.ID, viewer); err != nil {
+		logger.FromContext(ctx, ss.log).Error("operation failed", logger.Op(op), logger.Err(err))
+	}
+
+	return SharedVisualization{
+		Visualization: entry.visualization,
+		ETag:          entry.etag,
+		Protected:     link.HasPassword,
+		ExpiresAt:     link.ExpiresAt,
+	}, nil
+}
+
+// load returns the link and visualization behind shareID, from the cache or
+// else from the database. Missing links are not cached, so a link works as
+// soon as it is created. Misses read from the primary: an entry outlives
+// replication lag, so a stale replica row would be served for its whole TTL.
+func (ss *ShareLinkService) load(ctx context.Context, shareID uuid.UUID) (sharedEntry, error) {
+	if entry, ok := ss.cache.get(shareID); ok {
+		return entry, nil
+	}
+
+	generation := ss.cache.begin()
+	ctx = replica.WithPrimary(ctx)
+
+	link, err := ss.repo.GetByShareID(ctx, shareID)
+	if err != nil {
+		return sharedEntry{}, err
+	}
+
+	published, err := ss.visualizations.GetPublishedByID(ctx, link.VisualizationID)
+	if err != nil {
+		return sharedEntry{}, err
+	}
+	visualization := publicVisualization(published)
+
+	etag, err := visualizationETag(visualization)
+	if err != nil {
+		return sharedEntry{}, err
+	}
+
+	entry := sharedEntry{link: link, visualization: visualization, etag: etag}
+	ss.cache.add(shareID, entry, generation)
+
+	return entry, nil
+}
+
+// Unlock checks the password of a protected link and returns a sealed viewer
+// valid for ShareViewerTTL. Wrong passwords are throttled per link and per IP.
+func (ss *ShareLinkService) Unlock(ctx context.Context, shareID uuid.UUID, dto dto.ShareLinkUnlockDto, ip string) (string, error) {
+	const op = "service.ShareLinkService.Unlock"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	link, err := ss.active(ctx, shareID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !link.HasPassword {
+		return "", fmt.Errorf("%s: %w", op, ErrShareLinkNotProtected)
+	}
+
+	linkKey, ipKey := ss.guard.ShareLinkKey(shareID.String()), ss.guard.IPKey(ip)
+
+	wait, err := ss.guard.Check(ctx, linkKey, ipKey)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if wait > 0 {
+		return "", fmt.Errorf("%s: %w", op, &RateLimitError{RetryAfter: wait})
+	}
+
+	if !password.Compare(*link.PasswordHash, dto.Password) {
+		if _, err = ss.guard.Fail(ctx, linkKey, ipKey); err != nil {
+			logger.FromContext(ctx, ss.log).Error("operation failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+		}
+		return "", fmt.Errorf("%s: %w", op, ErrSharePasswordIncorrect)
+	}
+
+	if err = ss.guard.Reset(ctx, linkKey); err != nil {
+		logger.FromContext(ctx, ss.log).Error("operation failed", logger.Op(op), logger.Err(err))
+	}
+
+	raw, err := json.Marshal(shareViewer{LinkID: link.ID, ShareID: link.ShareID, ExpiresAt: time.Now().Add(ShareViewerTTL)})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ss.box.Seal(string(raw))
+}
+
+// active returns the link behind shareID unless it was revoked or expired.
+func (ss *ShareLinkService) active(ctx context.Context, shareID uuid.UUID) (models.ShareLink, error) {
+	link, err := ss.repo.GetByShareID(ctx, shareID)
+	if err != nil {
+		return link, err
+	}
+
+	if expired(link) {
+		return link, ErrShareLinkExpired
+	}
+
+	return link, nil
+}
+
+func expired(link models.ShareLink) bool {
+	return link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now())
+}
+
+func (ss *ShareLinkService) validViewer(link models.ShareLink, sealed string) bool {
+	if sealed == "" {
+		return false
+	}
+
+	raw, err := ss.box.Open(sealed)
+	if err != nil {
+		return false
+	}
+
+	var viewer shareViewer
+	if err = json.Unmarshal([]byte(raw), &viewer); err != nil {
+		return false
+	}
+
+	return viewer.LinkID == link.ID && viewer.ShareID == link.ShareID && time.Now().Before(viewer.ExpiresAt)
+}
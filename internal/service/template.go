@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/tracing"
 	"visualizer-go/internal/models"
 	"visualizer-go/internal/repository"
 
@@ -11,30 +12,95 @@ import (
 )
 
 type TemplateService struct {
-	log  *slog.Logger
-	repo repository.Template
+	log   *slog.Logger
+	repo  repository.Template
+	audit *AuditService
 }
 
-func NewTemplateService(log *slog.Logger, repo repository.Template) *TemplateService {
+func NewTemplateService(log *slog.Logger, repo repository.Template, audit *AuditService) *TemplateService {
 	return &TemplateService{
-		log:  log,
-		repo: repo,
+		log:   log,
+		repo:  repo,
+		audit: audit,
 	}
 }
 
 func (ts *TemplateService) GetAll(ctx context.Context, withCanvases bool) ([]models.Template, error) {
 	const op = "service.TemplateService.GetAll"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 	return ts.repo.GetAll(ctx, withCanvases)
 }
 func (ts *TemplateService) GetByID(ctx context.Context, templateID uuid.UUID) (models.Template, error) {
 	const op = "service.TemplateService.GetByID"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 	return ts.repo.GetByID(ctx, templateID)
 }
 func (ts *TemplateService) Create(ctx context.Context, dto dto.TemplateCreateDto) (uuid.UUID, error) {
 	const op = "service.TemplateService.Create"
-	return ts.repo.Create(ctx, dto)
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	templateID, err := ts.repo.Create(ctx, dto)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	ts.audit.Record(ctx, AuditEvent{
+		Action:     "template.created",
+		EntityType: EntityTemplate,
+		EntityID:   templateID.String(),
+		After:      map[string]any{"name": dto.Name, "description": dto.Description},
+	})
+
+	return templateID, nil
 }
 func (ts *TemplateService) Update(ctx context.Context, templateID uuid.UUID, dto dto.TemplateUpdateDto) error {
 	const op = "service.TemplateService.Update"
-	return ts.repo.Update(ctx, templateID, dto)
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	before, err := ts.repo.GetByID(ctx, templateID)
+	if err != nil {
+		return err
+	}
+
+	if err = ts.repo.Update(ctx, templateID, dto); err != nil {
+		return err
+	}
+
+	action := "template.updated"
+	if dto.IsDeleted != nil && *dto.IsDeleted {
+		action = "template.deleted"
+	}
+
+	ts.audit.Record(ctx, AuditEvent{
+		Action:     action,
+		EntityType: EntityTemplate,
+		EntityID:   templateID.String(),
+		Before:     map[string]any{"name": before.Name, "description": before.Description},
+		After:      templateChanges(dto),
+	})
+
+	return nil
+}
+
+// templateChanges summarizes an update for the audit log. Canvases are too
+// large to store, so only the fact that they changed is recorded.
+func templateChanges(dto dto.TemplateUpdateDto) map[string]any {
+	changes := make(map[string]any)
+	if dto.Name != nil {
+		changes["name"] = *dto.Name
+	}
+	if dto.Description != nil {
+		changes["description"] = *dto.Description
+	}
+	if dto.Canvases != nil {
+		changes["canvasesChanged"] = true
+	}
+	if dto.IsDeleted != nil {
+		changes["isDeleted"] = *dto.IsDeleted
+	}
+	return changes
 }
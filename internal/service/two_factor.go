@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/secretbox"
+	"visualizer-go/internal/lib/securetoken"
+	"visualizer-go/internal/lib/totp"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew accepts codes from the previous and the next period to
+	// tolerate clock drift between the server and the authenticator.
+	totpSkew = 1
+)
+
+var (
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrInvalidChallenge     = errors.New("two-factor challenge is invalid or has expired")
+	ErrTwoFactorUnavailable = errors.New("failed to process two-factor settings")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorRequiredError is returned by Login when the password was correct
+// but the account is protected by a second factor. The challenge token must
+// be exchanged for a session together with a code.
+type TwoFactorRequiredError struct {
+	ChallengeToken string
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+type TwoFactorService struct {
+	log    *slog.Logger
+	repo   repository.TwoFactor
+	audit  *AuditService
+	box    *secretbox.Box
+	issuer string
+}
+
+func NewTwoFactorService(log *slog.Logger, repo repository.TwoFactor, audit *AuditService, box *secretbox.Box, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		log:    log,
+		repo:   repo,
+		audit:  audit,
+		box:    box,
+		issuer: issuer,
+	}
+}
+
+// Enroll starts enrollment for the current user by generating a new secret.
+// Two-factor stays disabled until Confirm receives a valid code.
+func (tfs *TwoFactorService) Enroll(ctx context.Context) (TwoFactorEnrollment, error) {
+	const op = "service.TwoFactorService.Enroll"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return TwoFactorEnrollment{}, fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+	}
+
+	user, err := tfs.repo.Get(ctx, identity.UserID)
+	if err != nil {
+		return TwoFactorEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.TOTPEnabled {
+		return TwoFactorEnrollment{}, fmt.Errorf("%s: %w", op, repository.ErrTwoFactorAlreadyEnabled)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.FromContext(ctx, tfs.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return TwoFactorEnrollment{}, fmt.Errorf("%s: %w", op, ErrTwoFactorUnavailable)
+	}
+
+	sealed, err := tfs.box.Seal(secret)
+	if err != nil {
+		logger.FromContext(ctx, tfs.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return TwoFactorEnrollment{}, fmt.Errorf("%s: %w", op, ErrTwoFactorUnavailable)
+	}
+
+	if err = tfs.repo.SetSecret(ctx, user.ID, sealed); err != nil {
+		return TwoFactorEnrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(tfs.issuer, user.Username, secret),
+	}, nil
+}
+
+// Confirm enables two-factor once the user proves the authenticator works and
+// returns the recovery codes. They are only ever shown here.
+func (tfs *TwoFactorService) Confirm(ctx context.Context, dto dto.TwoFactorConfirmDto) ([]string, error) {
+	const op = "service.TwoFactorService.Confirm"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+	}
+
+	user, err := tfs.repo.Get(ctx, identity.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.TOTPEnabled {
+		return nil, fmt.Errorf("%s: %w", op, repository.ErrTwoFactorAlreadyEnabled)
+	}
+	if user.TOTPSecret == nil {
+		return nil, fmt.Errorf("%s: %w", op, repository.ErrTwoFactorNotPending)
+	}
+
+	secret, err := tfs.box.Open(*user.TOTPSecret)
+	if err != nil {
+		logger.FromContext(ctx, tfs.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return nil, fmt.Errorf("%s: %w", op, ErrTwoFactorUnavailable)
+	}
+
+	step, ok := totp.Validate(secret, dto.Code, time.Now(), totpSkew)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, repository.ErrTwoFactorCodeInvalid)
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			logger.FromContext(ctx, tfs.log).Error("operation failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return nil, fmt.Errorf("%s: %w", op, ErrTwoFactorUnavailable)
+		}
+		hashes[i] = securetoken.Hash(codes[i])
+	}
+
+	if err = tfs.repo.Enable(ctx, user.ID, step, hashes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tfs.audit.Record(ctx, AuditEvent{
+		Action:     "2fa.enabled",
+		EntityType: EntityUser,
+		EntityID:   user.ID.String(),
+	})
+
+	return codes, nil
+}
+
+// Disable turns two-factor off for the current user, who has to present a
+// valid code or recovery code once more.
+func (tfs *TwoFactorService) Disable(ctx context.Context, dto dto.TwoFactorCodeDto) error {
+	const op = "service.TwoFactorService.Disable"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, auth.ErrInvalidToken)
+	}
+
+	if err := tfs.verify(ctx, identity.UserID, dto); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tfs.repo.Disable(ctx, identity.UserID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tfs.audit.Record(ctx, AuditEvent{
+		Action:     "2fa.disabled",
+		EntityType: EntityUser,
+		EntityID:   identity.UserID.String(),
+	})
+
+	return nil
+}
+
+// Reset lets an admin turn off two-factor for a user who lost both the
+// authenticator and the recovery codes.
+func (tfs *TwoFactorService) Reset(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.TwoFactorService.Reset"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if err := tfs.repo.Disable(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tfs.audit.Record(ctx, AuditEvent{
+		Action:     "2fa.reset",
+		EntityType: EntityUser,
+		EntityID:   userID.String(),
+	})
+
+	return nil
+}
+
+// verify checks a TOTP or recovery code of a user with two-factor enabled
+// and consumes it so it cannot be used again.
+func (tfs *TwoFactorService) verify(ctx context.Context, userID uuid.UUID, dto dto.TwoFactorCodeDto) error {
+	user, err := tfs.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled || user.TOTPSecret == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if dto.Code == "" {
+		return tfs.repo.UseRecoveryCode(ctx, user.ID, securetoken.Hash(normalizeRecoveryCode(dto.RecoveryCode)))
+	}
+
+	secret, err := tfs.box.Open(*user.TOTPSecret)
+	if err != nil {
+		logger.FromContext(ctx, tfs.log).Error("operation failed", logger.Op("service.TwoFactorService.verify"), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return ErrTwoFactorUnavailable
+	}
+
+	step, ok := totp.Validate(secret, dto.Code, time.Now(), totpSkew)
+	if !ok {
+		return repository.ErrTwoFactorCodeInvalid
+	}
+
+	return tfs.repo.UseStep(ctx, user.ID, step)
+}
+
+// generateRecoveryCode returns a code such as "k3j9q-x2m4p". Codes are
+// normalized before hashing so case and separators do not matter.
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	if len(code) == 10 {
+		code = code[:5] + "-" + code[5:]
+	}
+	return code
+}
@@ -2,64 +2,536 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"log/slog"
 	"strings"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/bruteforce"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/password"
+	"visualizer-go/internal/lib/tracing"
 	"visualizer-go/internal/models"
 	"visualizer-go/internal/repository"
 )
 
+var (
+	ErrUserDeactivated     = errors.New("user is deactivated")
+	ErrSessionRevoked      = errors.New("session has been revoked")
+	ErrCannotModifySelf    = errors.New("cannot deactivate or delete your own account")
+	ErrInvalidReassignment = errors.New("visualizations must be reassigned to another active user")
+)
+
 type UserService struct {
-	log  *slog.Logger
-	repo repository.User
+	log       *slog.Logger
+	repo      repository.User
+	orgs      repository.Organization
+	audit     *AuditService
+	tokens    *auth.TokenManager
+	guard     *bruteforce.Guard
+	twoFactor *TwoFactorService
+	apiKeys   *APIKeyService
 }
 
-func NewUserService(log *slog.Logger, repo repository.User) *UserService {
+func NewUserService(log *slog.Logger, repo repository.User, orgs repository.Organization, audit *AuditService, tokens *auth.TokenManager, guard *bruteforce.Guard, twoFactor *TwoFactorService, apiKeys *APIKeyService) *UserService {
 	return &UserService{
-		log:  log,
-		repo: repo,
+		log:       log,
+		repo:      repo,
+		orgs:      orgs,
+		audit:     audit,
+		tokens:    tokens,
+		guard:     guard,
+		twoFactor: twoFactor,
+		apiKeys:   apiKeys,
 	}
 }
 
-func (us *UserService) Login(ctx context.Context, dto dto.UserLoginDto) (models.User, string, error) {
+// Login checks the password and issues a session token. For accounts with
+// two-factor enabled it instead returns a *TwoFactorRequiredError carrying a
+// challenge token for VerifyTwoFactor.
+func (us *UserService) Login(ctx context.Context, dto dto.UserLoginDto, ip string) (models.User, string, error) {
 	const op = "service.UserService.Login"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	userKey, ipKey := us.guard.UserKey(dto.Username), us.guard.IPKey(ip)
 
-	// TODO:
-	// hash compare passwords
+	wait, err := us.guard.Check(ctx, userKey, ipKey)
+	if err != nil {
+		logger.FromContext(ctx, us.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return models.User{}, "", fmt.Errorf("%s: %w", op, repository.ErrFailedToLogin)
+	}
+	if wait > 0 {
+		us.audit.Record(ctx, AuditEvent{
+			Action:     "login.throttled",
+			EntityType: EntityUser,
+			ActorName:  dto.Username,
+		})
+		return models.User{}, "", fmt.Errorf("%s: %w", op, &RateLimitError{RetryAfter: wait})
+	}
 
 	user, err := us.GetByUsername(ctx, dto.Username)
 	if err != nil {
-		us.log.Error(fmt.Sprintf("%s: %v", op, err))
+		logger.FromContext(ctx, us.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		password.CompareDummy(dto.Password)
+		us.loginFailed(ctx, op, dto.Username, ip, userKey, ipKey)
+		return models.User{}, "", fmt.Errorf("%s: %w", op, repository.ErrInvalidCredentials)
+	}
+	if !password.Compare(user.PasswordHash, dto.Password) {
+		us.loginFailed(ctx, op, dto.Username, ip, userKey, ipKey)
 		return models.User{}, "", fmt.Errorf("%s: %w", op, repository.ErrInvalidCredentials)
 	}
 
-	trimmedPasswordHash := strings.ReplaceAll(user.PasswordHash, " ", "")
+	if !user.IsActive {
+		return models.User{}, "", fmt.Errorf("%s: %w", op, ErrUserDeactivated)
+	}
 
-	if trimmedPasswordHash != dto.Password {
-		return models.User{}, "", fmt.Errorf("%s: %w", op, repository.ErrInvalidCredentials)
+	// The failure counter is only reset once the second factor is verified,
+	// otherwise a stolen password would allow unlimited code guesses.
+	if user.TOTPEnabled {
+		challenge, err := us.tokens.IssueChallenge(user.ID)
+		if err != nil {
+			logger.FromContext(ctx, us.log).Error("operation failed", logger.Op(op), logger.Err(err))
+			tracing.Fail(ctx, err)
+			return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+		}
+		return models.User{}, "", fmt.Errorf("%s: %w", op, &TwoFactorRequiredError{ChallengeToken: challenge})
+	}
+
+	if err = us.guard.Reset(ctx, userKey); err != nil {
+		logger.FromContext(ctx, us.log).Error("operation failed", logger.Op(op), logger.Err(err))
+	}
+
+	token, err := us.issueSession(ctx, user, false)
+	if err != nil {
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	us.loginSucceeded(ctx, user, "password")
+
+	return user, token, nil
+}
+
+// VerifyTwoFactor completes a login started by Login with a TOTP or recovery
+// code. Wrong codes count as failed logins for brute-force protection.
+func (us *UserService) VerifyTwoFactor(ctx context.Context, dto dto.TwoFactorVerifyDto, ip string) (models.User, string, error) {
+	const op = "service.UserService.VerifyTwoFactor"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	userID, err := us.tokens.ParseChallenge(dto.ChallengeToken)
+	if err != nil {
+		return models.User{}, "", fmt.Errorf("%s: %w: %w", op, ErrInvalidChallenge, err)
+	}
+
+	user, err := us.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return models.User{}, "", fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+		}
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	userKey, ipKey := us.guard.UserKey(user.Username), us.guard.IPKey(ip)
+
+	wait, err := us.guard.Check(ctx, userKey, ipKey)
+	if err != nil {
+		logger.FromContext(ctx, us.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return models.User{}, "", fmt.Errorf("%s: %w", op, repository.ErrFailedToLogin)
+	}
+	if wait > 0 {
+		us.audit.Record(ctx, AuditEvent{
+			Action:     "login.throttled",
+			EntityType: EntityUser,
+			EntityID:   user.ID.String(),
+			ActorID:    user.ID,
+			ActorName:  user.Username,
+		})
+		return models.User{}, "", fmt.Errorf("%s: %w", op, &RateLimitError{RetryAfter: wait})
+	}
+
+	if err = us.twoFactor.verify(ctx, user.ID, dto.TwoFactorCodeDto); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorCodeInvalid) {
+			us.loginFailed(ctx, op, user.Username, ip, userKey, ipKey)
+		}
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = us.guard.Reset(ctx, userKey); err != nil {
+		logger.FromContext(ctx, us.log).Error("operation failed", logger.Op(op), logger.Err(err))
+	}
+
+	if !user.IsActive {
+		return models.User{}, "", fmt.Errorf("%s: %w", op, ErrUserDeactivated)
+	}
+
+	token, err := us.issueSession(ctx, user, true)
+	if err != nil {
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	method := "totp"
+	if dto.RecoveryCode != "" {
+		method = "recovery_code"
+	}
+	us.loginSucceeded(ctx, user, method)
+
+	return user, token, nil
+}
+
+// issueSession signs an access token for user. The first organization the
+// user joined becomes the active one; others can be selected through the
+// organization switcher.
+func (us *UserService) issueSession(ctx context.Context, user models.User, mfa bool) (string, error) {
+	orgID := uuid.Nil
+	organizations, err := us.orgs.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(organizations) > 0 {
+		orgID = organizations[0].ID
+	}
+
+	token, err := us.tokens.Issue(user.ID, user.Role, orgID, mfa, user.SessionVersion)
+	if err != nil {
+		logger.FromContext(ctx, us.log).Error("operation failed", logger.Op("service.UserService.issueSession"), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return "", err
+	}
+
+	return token, nil
+}
+
+// loginSucceeded writes the audit entry for a completed login.
+func (us *UserService) loginSucceeded(ctx context.Context, user models.User, method string) {
+	us.audit.Record(ctx, AuditEvent{
+		Action:     "login.succeeded",
+		EntityType: EntityUser,
+		EntityID:   user.ID.String(),
+		After:      map[string]any{"method": method},
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+	})
+}
+
+// loginFailed records a failed attempt and writes an audit entry, plus a
+// separate one when the attempt triggered a lockout.
+func (us *UserService) loginFailed(ctx context.Context, op, username, ip string, keys ...bruteforce.Key) {
+	us.audit.Record(ctx, AuditEvent{
+		Action:     "login.failed",
+		EntityType: EntityUser,
+		ActorName:  username,
+	})
+
+	lockedOut, err := us.guard.Fail(ctx, keys...)
+	if err != nil {
+		logger.FromContext(ctx, us.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return
+	}
+
+	if lockedOut {
+		us.audit.Record(ctx, AuditEvent{
+			Action:     "login.locked",
+			EntityType: EntityUser,
+			ActorName:  username,
+		})
+	}
+}
+
+// Unlock clears the failed login counter of a user locked out by brute-force protection.
+func (us *UserService) Unlock(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.UserService.Unlock"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	user, err := us.repo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = us.guard.Reset(ctx, us.guard.UserKey(user.Username)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	us.audit.Record(ctx, AuditEvent{
+		Action:     "login.unlocked",
+		EntityType: EntityUser,
+		EntityID:   user.ID.String(),
+		Before:     map[string]any{"username": user.Username},
+	})
+
+	return nil
+}
+
+// Authenticate resolves a bearer token, either a session token or an API key,
+// to the identity of an active user. The role is taken from the database so
+// role changes apply immediately.
+func (us *UserService) Authenticate(ctx context.Context, token string) (auth.Identity, error) {
+	const op = "service.UserService.Authenticate"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		return us.authenticateAPIKey(ctx, token)
+	}
+
+	identity, err := us.tokens.Parse(token)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := us.repo.GetByID(ctx, identity.UserID)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.IsActive {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, ErrUserDeactivated)
+	}
+
+	if identity.SessionVersion != user.SessionVersion {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, ErrSessionRevoked)
 	}
 
-	return user, "test_token_123", nil
+	identity.Role = user.Role
+	identity.Username = user.Username
+
+	if identity.OrgID != uuid.Nil {
+		member, err := us.orgs.GetMembership(ctx, identity.OrgID, user.ID)
+		if err != nil {
+			return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+		}
+		identity.OrgRole = member.Role
+		identity.AdminRequiresMFA = member.RequireAdminTwoFactor
+	}
+
+	return identity, nil
 }
 
+// authenticateAPIKey resolves an API key to an identity acting in the
+// organization the key was created for. Keys stop working as soon as the user
+// is deactivated or leaves that organization.
+func (us *UserService) authenticateAPIKey(ctx context.Context, secret string) (auth.Identity, error) {
+	const op = "service.UserService.authenticateAPIKey"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	key, err := us.apiKeys.resolve(ctx, secret)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := us.repo.GetByID(ctx, key.UserID)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !user.IsActive {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, ErrUserDeactivated)
+	}
+
+	member, err := us.orgs.GetMembership(ctx, key.OrgID, user.ID)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return auth.Identity{
+		UserID:           user.ID,
+		Username:         user.Username,
+		Role:             user.Role,
+		OrgID:            key.OrgID,
+		OrgRole:          member.Role,
+		AdminRequiresMFA: member.RequireAdminTwoFactor,
+		APIKeyID:         key.ID,
+		Scopes:           key.Scopes,
+	}, nil
+}
+
+func (us *UserService) GetAll(ctx context.Context, search string, limit, offset int) ([]models.User, int, error) {
+	const op = "service.UserService.GetAll"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	return us.repo.GetAll(ctx, search, limit, offset)
+}
+
+// GetByID returns a user the caller may see: themselves, anyone for global
+// admins, and otherwise only members of the active organization. Other users
+// are reported as not found, so their existence does not leak across
+// organizations.
 func (us *UserService) GetByID(ctx context.Context, userID uuid.UUID) (models.User, error) {
 	const op = "service.UserService.GetByID"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if identity, ok := auth.FromContext(ctx); ok && identity.UserID != userID && identity.Role != models.RoleAdmin {
+		if identity.OrgID == uuid.Nil {
+			return models.User{}, fmt.Errorf("%s: %w", op, repository.ErrUserNotFound)
+		}
+		if _, err := us.orgs.GetMembership(ctx, identity.OrgID, userID); err != nil {
+			if errors.Is(err, repository.ErrMemberNotFound) {
+				return models.User{}, fmt.Errorf("%s: %w", op, repository.ErrUserNotFound)
+			}
+			return models.User{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	return us.repo.GetByID(ctx, userID)
 }
 
 func (us *UserService) GetByUsername(ctx context.Context, username string) (models.User, error) {
 	const op = "service.UserService.GetByUsername"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 	return us.repo.GetByUsername(ctx, username)
 }
 
 func (us *UserService) Create(ctx context.Context, dto dto.UserCreateDto) error {
 	const op = "service.UserService.Create"
-	return us.repo.Create(ctx, dto)
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	hash, err := password.Hash(dto.Password)
+	if err != nil {
+		logger.FromContext(ctx, us.log).Error("operation failed", logger.Op(op), logger.Err(err))
+		tracing.Fail(ctx, err)
+		return fmt.Errorf("%s: %w", op, repository.ErrFailedToCreateUser)
+	}
+	dto.Password = hash
+
+	userID, err := us.repo.Create(ctx, dto)
+	if err != nil {
+		return err
+	}
+
+	us.audit.Record(ctx, AuditEvent{
+		Action:     "user.created",
+		EntityType: EntityUser,
+		EntityID:   userID.String(),
+		After:      map[string]any{"username": dto.Username, "email": dto.Email},
+	})
+
+	return nil
 }
 
 func (us *UserService) Update(ctx context.Context, userID uuid.UUID, dto dto.UserUpdateDto) error {
 	const op = "service.UserService.Update"
-	return us.repo.Update(ctx, userID, dto)
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	before, err := us.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = us.repo.Update(ctx, userID, dto); err != nil {
+		return err
+	}
+
+	us.audit.Record(ctx, AuditEvent{
+		Action:     "user.updated",
+		EntityType: EntityUser,
+		EntityID:   userID.String(),
+		Before:     map[string]any{"role": before.Role},
+		After:      dto,
+	})
+
+	return nil
+}
+
+func (us *UserService) Deactivate(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.UserService.Deactivate"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if identity, ok := auth.FromContext(ctx); ok && identity.UserID == userID {
+		return fmt.Errorf("%s: %w", op, ErrCannotModifySelf)
+	}
+
+	if err := us.repo.SetActive(ctx, userID, false); err != nil {
+		return err
+	}
+
+	us.audit.Record(ctx, AuditEvent{
+		Action:     "user.deactivated",
+		EntityType: EntityUser,
+		EntityID:   userID.String(),
+	})
+
+	return nil
+}
+
+func (us *UserService) Reactivate(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.UserService.Reactivate"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if err := us.repo.SetActive(ctx, userID, true); err != nil {
+		return err
+	}
+
+	us.audit.Record(ctx, AuditEvent{
+		Action:     "user.reactivated",
+		EntityType: EntityUser,
+		EntityID:   userID.String(),
+	})
+
+	return nil
+}
+
+// Delete removes a user, handing their visualizations over to reassignTo.
+// When reassignTo is uuid.Nil the acting user becomes the new owner.
+func (us *UserService) Delete(ctx context.Context, userID uuid.UUID, reassignTo uuid.UUID) error {
+	const op = "service.UserService.Delete"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if ok && identity.UserID == userID {
+		return fmt.Errorf("%s: %w", op, ErrCannotModifySelf)
+	}
+
+	if reassignTo == uuid.Nil {
+		reassignTo = identity.UserID
+	}
+
+	if reassignTo == userID {
+		return fmt.Errorf("%s: %w", op, ErrInvalidReassignment)
+	}
+
+	newOwner, err := us.repo.GetByID(ctx, reassignTo)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidReassignment)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !newOwner.IsActive {
+		return fmt.Errorf("%s: %w", op, ErrInvalidReassignment)
+	}
+
+	user, err := us.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err = us.repo.Delete(ctx, userID, reassignTo); err != nil {
+		return err
+	}
+
+	us.audit.Record(ctx, AuditEvent{
+		Action:     "user.deleted",
+		EntityType: EntityUser,
+		EntityID:   userID.String(),
+		Before:     map[string]any{"username": user.Username, "role": user.Role},
+		After:      map[string]any{"reassignedTo": reassignTo},
+	})
+
+	return nil
 }
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/lib/logger"
+	"visualizer-go/internal/lib/metrics"
+	"visualizer-go/internal/lib/tracing"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ViewCounterStats describes the flushes done so far.
+type ViewCounterStats struct {
+	Pending           int
+	Flushes           uint64
+	FailedFlushes     uint64
+	FlushedViews      uint64
+	DroppedViews      uint64
+	LastFlushDuration time.Duration
+	MaxFlushDuration  time.Duration
+	TotalFlushTime    time.Duration
+}
+
+// ViewCounter buffers view counts in memory and writes them in batches, so a
+// busy share page does not turn into one UPDATE per view on the same row.
+// Reaching MaxPending buffered views triggers an immediate flush, which bounds
+// what a crash can lose. While flushes fail, MaxPending is a hard cap: views
+// beyond it, including those held by a flush in progress, are dropped and
+// counted rather than buffered without limit.
+type ViewCounter struct {
+	log            *slog.Logger
+	visualizations repository.Visualization
+	shareLinks     repository.ShareLink
+	cfg            config.ViewCounter
+
+	mu                  sync.Mutex
+	visualizationCounts map[uuid.UUID]int
+	shareLinkCounts     map[uuid.UUID]int
+	pending             int
+	inflight            int
+	failing             bool
+	stats               ViewCounterStats
+
+	flushNow chan struct{}
+	running  atomic.Bool
+}
+
+func NewViewCounter(log *slog.Logger, visualizations repository.Visualization, shareLinks repository.ShareLink, cfg config.ViewCounter) *ViewCounter {
+	return &ViewCounter{
+		log:                 log,
+		visualizations:      visualizations,
+		shareLinks:          shareLinks,
+		cfg:                 cfg,
+		visualizationCounts: make(map[uuid.UUID]int),
+		shareLinkCounts:     make(map[uuid.UUID]int),
+		flushNow:            make(chan struct{}, 1),
+	}
+}
+
+// Add counts one view of a visualization and, if given, of the share link it
+// was opened through.
+func (vc *ViewCounter) Add(visualizationID uuid.UUID, shareLinkID *uuid.UUID) {
+	vc.mu.Lock()
+	if vc.failing && vc.pending+vc.inflight >= vc.cfg.MaxPending {
+		vc.stats.DroppedViews++
+		vc.mu.Unlock()
+		metrics.ViewCounterDroppedViews.Inc()
+		return
+	}
+	vc.visualizationCounts[visualizationID]++
+	if shareLinkID != nil {
+		vc.shareLinkCounts[*shareLinkID]++
+	}
+	vc.pending++
+	full := vc.pending >= vc.cfg.MaxPending
+	vc.mu.Unlock()
+
+	if full {
+		select {
+		case vc.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Stats returns a snapshot of the flush statistics.
+func (vc *ViewCounter) Stats() ViewCounterStats {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	stats := vc.stats
+	stats.Pending = vc.pending
+	return stats
+}
+
+// Run flushes on every interval until ctx is cancelled, then flushes what is
+// left once more.
+func (vc *ViewCounter) Run(ctx context.Context) {
+	const op = "service.ViewCounter.Run"
+
+	log := vc.log.With(slog.String("op", op))
+
+	log.Info("starting view counter...", slog.Duration("interval", vc.cfg.FlushInterval))
+
+	vc.running.Store(true)
+	defer vc.running.Store(false)
+
+	ticker := time.NewTicker(vc.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	var failing bool
+	for {
+		// While the database is failing, a full buffer waits for the next
+		// tick instead of retrying on every view.
+		flushNow := vc.flushNow
+		if failing {
+			flushNow = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			// ctx is already cancelled; give the last flush its own deadline.
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), vc.cfg.FlushTimeout)
+			if err := vc.Flush(flushCtx); err != nil {
+				log.Error("lost buffered view counts", slog.Int("pending", vc.Stats().Pending))
+			}
+			cancel()
+			log.Info("view counter stopped")
+			return
+		case <-ticker.C:
+		case <-flushNow:
+		}
+
+		flushCtx, cancel := context.WithTimeout(ctx, vc.cfg.FlushTimeout)
+		failing = vc.Flush(flushCtx) != nil
+		cancel()
+	}
+}
+
+// Running reports whether Run is flushing periodically.
+func (vc *ViewCounter) Running() bool {
+	return vc.running.Load()
+}
+
+// Flush writes the buffered counts. Counts that cannot be written are put
+// back and retried on the next flush.
+func (vc *ViewCounter) Flush(ctx context.Context) error {
+	const op = "service.ViewCounter.Flush"
+
+	vc.mu.Lock()
+	visualizationCounts, shareLinkCounts, pending := vc.visualizationCounts, vc.shareLinkCounts, vc.pending
+	vc.visualizationCounts = make(map[uuid.UUID]int)
+	vc.shareLinkCounts = make(map[uuid.UUID]int)
+	vc.pending = 0
+	vc.inflight = pending
+	vc.mu.Unlock()
+
+	// Share link counts may be left over from a flush whose visualization
+	// counts were written, so pending alone does not tell.
+	if len(visualizationCounts) == 0 && len(shareLinkCounts) == 0 {
+		return nil
+	}
+
+	// Started only for non-empty flushes so idle ticks leave no traces.
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	start := time.Now()
+
+	var err error
+	if len(visualizationCounts) > 0 {
+		if err = vc.visualizations.AddViewCounts(ctx, visualizationCounts); err == nil {
+			visualizationCounts = nil
+		}
+	}
+	if len(shareLinkCounts) > 0 {
+		if linkErr := vc.shareLinks.AddViewCounts(ctx, shareLinkCounts); linkErr != nil {
+			err = linkErr
+		} else {
+			shareLinkCounts = nil
+		}
+	}
+
+	elapsed := time.Since(start)
+
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.ViewCounterFlushDuration.WithLabelValues(result).Observe(elapsed.Seconds())
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.inflight = 0
+	vc.failing = err != nil
+	vc.stats.Flushes++
+	vc.stats.LastFlushDuration = elapsed
+	vc.stats.TotalFlushTime += elapsed
+	vc.stats.MaxFlushDuration = max(vc.stats.MaxFlushDuration, elapsed)
+
+	if visualizationCounts == nil {
+		vc.stats.FlushedViews += uint64(pending)
+		metrics.ViewCounterFlushedViews.Add(float64(pending))
+	} else {
+		for id, n := range visualizationCounts {
+			vc.visualizationCounts[id] += n
+		}
+		vc.pending += pending
+	}
+	for id, n := range shareLinkCounts {
+		vc.shareLinkCounts[id] += n
+	}
+
+	if err != nil {
+		vc.stats.FailedFlushes++
+		vc.log.Error("failed to flush view counts", logger.Op(op), logger.Err(err), slog.Int("pending", vc.pending),
+			slog.Uint64("dropped", vc.stats.DroppedViews), slog.Duration("duration", elapsed))
+		return err
+	}
+
+	vc.log.Debug("flushed view counts", slog.Int("views", pending), slog.Duration("duration", elapsed))
+	return nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+	"visualizer-go/internal/lib/config"
+	"visualizer-go/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var errDatabaseDown = errors.New("database is down")
+
+// countStore stands in for the visualization and share link repositories,
+// failing AddViewCounts while err is set.
+type countStore struct {
+	mu     sync.Mutex
+	err    error
+	counts map[uuid.UUID]int
+}
+
+func (s *countStore) AddViewCounts(_ context.Context, counts map[uuid.UUID]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if s.counts == nil {
+		s.counts = make(map[uuid.UUID]int)
+	}
+	for id, n := range counts {
+		s.counts[id] += n
+	}
+	return nil
+}
+
+func (s *countStore) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+type visualizationCounts struct {
+	repository.Visualization
+	store *countStore
+}
+
+func (v visualizationCounts) AddViewCounts(ctx context.Context, counts map[uuid.UUID]int) error {
+	return v.store.AddViewCounts(ctx, counts)
+}
+
+type shareLinkCounts struct {
+	repository.ShareLink
+	store *countStore
+}
+
+func (l shareLinkCounts) AddViewCounts(ctx context.Context, counts map[uuid.UUID]int) error {
+	return l.store.AddViewCounts(ctx, counts)
+}
+
+// newTestViewCounter returns a counter with the stores behind its
+// visualization and share link repositories.
+func newTestViewCounter(maxPending int) (*ViewCounter, *countStore, *countStore) {
+	store, links := &countStore{}, &countStore{}
+	vc := NewViewCounter(slog.New(slog.NewTextHandler(io.Discard, nil)),
+		visualizationCounts{store: store}, shareLinkCounts{store: links},
+		config.ViewCounter{FlushInterval: time.Hour, FlushTimeout: time.Second, MaxPending: maxPending})
+	return vc, store, links
+}
+
+func TestViewCounterCapsPendingWhileFlushesFail(t *testing.T) {
+	ctx := context.Background()
+	vc, store, _ := newTestViewCounter(3)
+	id := uuid.New()
+
+	store.setErr(errDatabaseDown)
+
+	// Before a flush has failed the buffer is not capped.
+	for range 4 {
+		vc.Add(id, nil)
+	}
+	if err := vc.Flush(ctx); !errors.Is(err, errDatabaseDown) {
+		t.Fatalf("Flush error = %v, want %v", err, errDatabaseDown)
+	}
+
+	for range 5 {
+		vc.Add(id, nil)
+	}
+
+	stats := vc.Stats()
+	if stats.Pending != 4 {
+		t.Errorf("Pending = %d, want 4 kept from the failed flush", stats.Pending)
+	}
+	if stats.DroppedViews != 5 {
+		t.Errorf("DroppedViews = %d, want 5", stats.DroppedViews)
+	}
+
+	store.setErr(nil)
+	if err := vc.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := store.counts[id]; got != 4 {
+		t.Errorf("stored count = %d, want 4", got)
+	}
+
+	// A successful flush lifts the cap again.
+	vc.Add(id, nil)
+	if stats := vc.Stats(); stats.Pending != 1 || stats.DroppedViews != 5 {
+		t.Errorf("after recovery Pending = %d, DroppedViews = %d, want 1 and 5", stats.Pending, stats.DroppedViews)
+	}
+}
+
+func TestViewCounterKeepsCountsOfFailedFlush(t *testing.T) {
+	ctx := context.Background()
+	vc, store, _ := newTestViewCounter(10)
+	a, b := uuid.New(), uuid.New()
+
+	store.setErr(errDatabaseDown)
+	vc.Add(a, nil)
+	vc.Add(a, nil)
+	vc.Add(b, nil)
+	if err := vc.Flush(ctx); err == nil {
+		t.Fatal("Flush succeeded with a failing repository")
+	}
+
+	store.setErr(nil)
+	if err := vc.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if store.counts[a] != 2 || store.counts[b] != 1 {
+		t.Fatalf("stored counts = %v, want a=2 b=1", store.counts)
+	}
+	if stats := vc.Stats(); stats.FailedFlushes != 1 || stats.FlushedViews != 3 {
+		t.Fatalf("FailedFlushes = %d, FlushedViews = %d, want 1 and 3", stats.FailedFlushes, stats.FlushedViews)
+	}
+}
+
+func TestViewCounterRetriesShareLinkCountsAlone(t *testing.T) {
+	ctx := context.Background()
+	vc, store, links := newTestViewCounter(10)
+	id, linkID := uuid.New(), uuid.New()
+
+	links.setErr(errDatabaseDown)
+	vc.Add(id, &linkID)
+	vc.Add(id, &linkID)
+	if err := vc.Flush(ctx); !errors.Is(err, errDatabaseDown) {
+		t.Fatalf("Flush error = %v, want %v", err, errDatabaseDown)
+	}
+	if got := store.counts[id]; got != 2 {
+		t.Fatalf("stored visualization count = %d, want 2", got)
+	}
+
+	// No views came in since, but the link counts are still owed.
+	links.setErr(nil)
+	if err := vc.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := links.counts[linkID]; got != 2 {
+		t.Errorf("stored share link count = %d, want 2", got)
+	}
+	if got := store.counts[id]; got != 2 {
+		t.Errorf("stored visualization count = %d, want 2 after the retry", got)
+	}
+}
@@ -1,61 +1,384 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 	"visualizer-go/internal/dto"
+	"visualizer-go/internal/lib/auth"
+	"visualizer-go/internal/lib/tracing"
 	"visualizer-go/internal/models"
 	"visualizer-go/internal/repository"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrVisualizationLocked = errors.New("visualization cannot be edited while in review or archived")
+	ErrSelfReview          = errors.New("you cannot review your own submission")
+	ErrScheduleInPast      = errors.New("scheduled times must be in the future")
+	ErrScheduleOrder       = errors.New("unpublish time must be after publish time")
+)
+
 type VisualizationService struct {
-	log  *slog.Logger
-	repo repository.Visualization
+	log     *slog.Logger
+	repo    repository.Visualization
+	reviews repository.Review
+	audit   *AuditService
+	shares  *ShareCache
 }
 
-func NewVisualizationService(log *slog.Logger, repo repository.Visualization) *VisualizationService {
+func NewVisualizationService(log *slog.Logger, repo repository.Visualization, reviews repository.Review, audit *AuditService, shares *ShareCache) *VisualizationService {
 	return &VisualizationService{
-		log:  log,
-		repo: repo,
+		log:     log,
+		repo:    repo,
+		reviews: reviews,
+		audit:   audit,
+		shares:  shares,
 	}
 }
 
 func (vs *VisualizationService) GetAll(ctx context.Context) ([]models.Visualization, error) {
 	const op = "service.VisualizationService.GetAll"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 	return vs.repo.GetAll(ctx)
 }
 func (vs *VisualizationService) GetByTemplateID(ctx context.Context, templateID uuid.UUID) ([]models.Visualization, error) {
 	const op = "service.VisualizationService.GetByTemplateID"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 	return vs.repo.GetByTemplateID(ctx, templateID)
 }
 
 func (vs *VisualizationService) GetByID(ctx context.Context, visualizationID uuid.UUID) (models.Visualization, error) {
 	const op = "service.VisualizationService.GetByID"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
 	return vs.repo.GetByID(ctx, visualizationID)
 }
 
-func (vs *VisualizationService) GetByShareID(ctx context.Context, shareID uuid.UUID) (models.Visualization, error) {
-	const op = "service.VisualizationService.GetByShareID"
-	return vs.repo.GetByShareID(ctx, shareID)
-}
-
 func (vs *VisualizationService) Create(ctx context.Context, dto dto.VisualizationCreateDto) (uuid.UUID, error) {
 	const op = "service.VisualizationService.Create"
-	return vs.repo.Create(ctx, dto)
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	visualizationID, err := vs.repo.Create(ctx, dto)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	vs.audit.Record(ctx, AuditEvent{
+		Action:     "visualization.created",
+		EntityType: EntityVisualization,
+		EntityID:   visualizationID.String(),
+		After:      map[string]any{"name": dto.Name, "templateId": dto.TemplateID},
+	})
+
+	return visualizationID, nil
 }
+
+// Update edits the working copy. Approved or published visualizations fall
+// back to draft; viewers keep seeing the published snapshot meanwhile.
 func (vs *VisualizationService) Update(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationUpdateDto) error {
 	const op = "service.VisualizationService.Update"
-	return vs.repo.Update(ctx, visualizationID, dto)
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	before, err := vs.repo.GetByID(ctx, visualizationID)
+	if err != nil {
+		return err
+	}
+
+	if before.Status == models.StatusInReview || before.Status == models.StatusArchived {
+		return fmt.Errorf("%s: %w", op, ErrVisualizationLocked)
+	}
+
+	if err = vs.repo.Update(ctx, visualizationID, dto); err != nil {
+		return err
+	}
+
+	vs.shares.InvalidateVisualization(visualizationID)
+
+	vs.audit.Record(ctx, AuditEvent{
+		Action:     "visualization.updated",
+		EntityType: EntityVisualization,
+		EntityID:   visualizationID.String(),
+		Before:     visualizationSummary(before),
+		After:      visualizationChanges(dto),
+	})
+
+	return nil
 }
 
-func (vs *VisualizationService) IncrementViewCount(ctx context.Context, visualizationID uuid.UUID) error {
-	const op = "service.VisualizationService.IncrementViewCount"
-	return vs.repo.IncrementViewCount(ctx, visualizationID)
+// Submit sends a draft to the given reviewers. Every reviewer has to approve
+// before the visualization can be published.
+func (vs *VisualizationService) Submit(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationSubmitDto) error {
+	const op = "service.VisualizationService.Submit"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+
+	reviewers := slices.Clone(dto.Reviewers)
+	slices.SortFunc(reviewers, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	reviewers = slices.Compact(reviewers)
+
+	if slices.Contains(reviewers, identity.UserID) {
+		return fmt.Errorf("%s: %w", op, ErrSelfReview)
+	}
+
+	if err := vs.reviews.Submit(ctx, visualizationID, identity.UserID, reviewers); err != nil {
+		return err
+	}
+
+	vs.audit.Record(ctx, AuditEvent{
+		Action:     "visualization.submitted",
+		EntityType: EntityVisualization,
+		EntityID:   visualizationID.String(),
+		Before:     map[string]any{"status": models.StatusDraft},
+		After:      map[string]any{"status": models.StatusInReview, "reviewers": reviewers},
+	})
+
+	return nil
+}
+
+// Approve records the caller's approval. The visualization becomes approved
+// once the last required reviewer has approved it.
+func (vs *VisualizationService) Approve(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationReviewDto) (string, error) {
+	const op = "service.VisualizationService.Approve"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	return vs.decide(ctx, op, visualizationID, models.ReviewApproved, dto.Comment)
+}
+
+// Reject sends the visualization back to draft with the reviewer's comment.
+func (vs *VisualizationService) Reject(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationRejectDto) (string, error) {
+	const op = "service.VisualizationService.Reject"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	return vs.decide(ctx, op, visualizationID, models.ReviewRejected, dto.Comment)
+}
+
+func (vs *VisualizationService) decide(ctx context.Context, op string, visualizationID uuid.UUID, decision, comment string) (string, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+
+	status, err := vs.reviews.Decide(ctx, visualizationID, identity.UserID, decision, comment)
+	if err != nil {
+		return "", err
+	}
+
+	vs.audit.Record(ctx, AuditEvent{
+		Action:     "visualization." + decision,
+		EntityType: EntityVisualization,
+		EntityID:   visualizationID.String(),
+		Before:     map[string]any{"status": models.StatusInReview},
+		After:      map[string]any{"status": status, "comment": comment},
+	})
+
+	return status, nil
+}
+
+func (vs *VisualizationService) GetReviews(ctx context.Context, visualizationID uuid.UUID) ([]models.VisualizationReview, error) {
+	const op = "service.VisualizationService.GetReviews"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	return vs.reviews.GetAll(ctx, visualizationID)
+}
+
+// Publish freezes the approved working copy and makes it visible through
+// share links and embeds. Changing what is publicly visible needs its own
+// API key scope.
+func (vs *VisualizationService) Publish(ctx context.Context, visualizationID uuid.UUID) error {
+	const op = "service.VisualizationService.Publish"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+	if !identity.HasScope(auth.ScopePublish) {
+		return fmt.Errorf("%s: %w", op, auth.ErrInsufficientScope)
+	}
+
+	if err := vs.repo.Publish(ctx, visualizationID, identity.UserID); err != nil {
+		return err
+	}
+
+	vs.shares.InvalidateVisualization(visualizationID)
+
+	vs.audit.Record(ctx, AuditEvent{
+		Action:     "visualization.published",
+		EntityType: EntityVisualization,
+		EntityID:   visualizationID.String(),
+		Before:     map[string]any{"status": models.StatusApproved},
+		After:      map[string]any{"status": models.StatusPublished},
+	})
+
+	return nil
+}
+
+// Archive takes a visualization offline and locks it against edits.
+func (vs *VisualizationService) Archive(ctx context.Context, visualizationID uuid.UUID) error {
+	const op = "service.VisualizationService.Archive"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if identity, ok := auth.FromContext(ctx); ok && !identity.HasScope(auth.ScopePublish) {
+		return fmt.Errorf("%s: %w", op, auth.ErrInsufficientScope)
+	}
+
+	return vs.transition(ctx, visualizationID, "visualization.archived",
+		[]string{models.StatusDraft, models.StatusApproved, models.StatusPublished}, models.StatusArchived)
+}
+
+// Restore brings an archived visualization back as a draft. It stays offline
+// until it is reviewed and published again.
+func (vs *VisualizationService) Restore(ctx context.Context, visualizationID uuid.UUID) error {
+	const op = "service.VisualizationService.Restore"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	return vs.transition(ctx, visualizationID, "visualization.restored",
+		[]string{models.StatusArchived}, models.StatusDraft)
+}
+
+// Schedule sets when the visualization goes live and when it is taken
+// offline again. A scheduled publish fires once the visualization is
+// approved; until then it stays pending.
+func (vs *VisualizationService) Schedule(ctx context.Context, visualizationID uuid.UUID, dto dto.VisualizationScheduleDto) error {
+	const op = "service.VisualizationService.Schedule"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, repository.ErrNoActiveOrganization)
+	}
+	if !identity.HasScope(auth.ScopePublish) {
+		return fmt.Errorf("%s: %w", op, auth.ErrInsufficientScope)
+	}
+
+	now := time.Now()
+	if (dto.PublishAt != nil && !dto.PublishAt.After(now)) || (dto.UnpublishAt != nil && !dto.UnpublishAt.After(now)) {
+		return fmt.Errorf("%s: %w", op, ErrScheduleInPast)
+	}
+	if dto.PublishAt != nil && dto.UnpublishAt != nil && !dto.UnpublishAt.After(*dto.PublishAt) {
+		return fmt.Errorf("%s: %w", op, ErrScheduleOrder)
+	}
+
+	before, err := vs.repo.GetByID(ctx, visualizationID)
+	if err != nil {
+		return err
+	}
+
+	if err = vs.repo.Schedule(ctx, visualizationID, dto.PublishAt, dto.UnpublishAt, identity.UserID); err != nil {
+		return err
+	}
+
+	vs.audit.Record(ctx, AuditEvent{
+		Action:     "visualization.scheduled",
+		EntityType: EntityVisualization,
+		EntityID:   visualizationID.String(),
+		Before:     map[string]any{"publishAt": before.PublishAt, "unpublishAt": before.UnpublishAt},
+		After:      map[string]any{"publishAt": dto.PublishAt, "unpublishAt": dto.UnpublishAt},
+	})
+
+	return nil
+}
+
+func (vs *VisualizationService) transition(ctx context.Context, visualizationID uuid.UUID, action string, from []string, to string) error {
+	before, err := vs.repo.GetByID(ctx, visualizationID)
+	if err != nil {
+		return err
+	}
+
+	if err = vs.repo.Transition(ctx, visualizationID, from, to); err != nil {
+		return err
+	}
+
+	vs.shares.InvalidateVisualization(visualizationID)
+
+	vs.audit.Record(ctx, AuditEvent{
+		Action:     action,
+		EntityType: EntityVisualization,
+		EntityID:   visualizationID.String(),
+		Before:     map[string]any{"status": before.Status, "published": before.IsPublished},
+		After:      map[string]any{"status": to},
+	})
+
+	return nil
 }
 
 func (vs *VisualizationService) Delete(ctx context.Context, visualizationID uuid.UUID) error {
 	const op = "service.VisualizationService.Delete"
-	return vs.repo.Delete(ctx, visualizationID)
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	before, err := vs.repo.GetByID(ctx, visualizationID)
+	if err != nil {
+		return err
+	}
+
+	if err = vs.repo.Delete(ctx, visualizationID); err != nil {
+		return err
+	}
+
+	vs.shares.InvalidateVisualization(visualizationID)
+
+	vs.audit.Record(ctx, AuditEvent{
+		Action:     "visualization.deleted",
+		EntityType: EntityVisualization,
+		EntityID:   visualizationID.String(),
+		Before:     visualizationSummary(before),
+	})
+
+	return nil
+}
+
+func visualizationSummary(v models.Visualization) map[string]any {
+	return map[string]any{
+		"name":        v.Name,
+		"description": v.Description,
+		"client":      v.Client,
+		"published":   v.IsPublished,
+		"status":      v.Status,
+		"templateId":  v.TemplateID,
+		"userId":      v.UserID,
+	}
+}
+
+// visualizationChanges summarizes an update for the audit log. Canvases are
+// too large to store, so only the fact that they changed is recorded.
+func visualizationChanges(dto dto.VisualizationUpdateDto) map[string]any {
+	changes := make(map[string]any)
+	if dto.Name != nil {
+		changes["name"] = *dto.Name
+	}
+	if dto.Description != nil {
+		changes["description"] = *dto.Description
+	}
+	if dto.Client != nil {
+		changes["client"] = *dto.Client
+	}
+	if dto.TemplateID != nil {
+		changes["templateId"] = *dto.TemplateID
+	}
+	if dto.Tenant != nil {
+		changes["tenant"] = *dto.Tenant
+	}
+	if dto.Canvases != nil {
+		changes["canvasesChanged"] = true
+	}
+	return changes
 }
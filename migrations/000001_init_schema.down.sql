@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS visualizations;
+DROP TABLE IF EXISTS templates;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,42 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE TABLE IF NOT EXISTS users (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username      VARCHAR(64)  NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    role          VARCHAR(32)  NOT NULL DEFAULT 'viewer',
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS templates (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        VARCHAR(255) NOT NULL,
+    description TEXT,
+    canvases    JSONB,
+    is_deleted  BOOLEAN     NOT NULL DEFAULT FALSE,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS visualizations (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name           VARCHAR(255) NOT NULL,
+    description    TEXT,
+    client         VARCHAR(255),
+    is_published   BOOLEAN     NOT NULL DEFAULT FALSE,
+    share_id       UUID        NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    user_id        UUID        NOT NULL REFERENCES users (id),
+    template_id    UUID REFERENCES templates (id),
+    canvases       JSONB,
+    is_saved       BOOLEAN     NOT NULL DEFAULT FALSE,
+    is_publishable BOOLEAN     NOT NULL DEFAULT FALSE,
+    tenant         VARCHAR(255),
+    view_count     INTEGER     NOT NULL DEFAULT 0,
+    viewed_at      TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS visualizations_template_id_idx ON visualizations (template_id);
+CREATE INDEX IF NOT EXISTS visualizations_user_id_idx ON visualizations (user_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_username_lower_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS session_version,
+    DROP COLUMN IF EXISTS is_active;
@@ -0,0 +1,7 @@
+-- Tokens carry the session version they were issued with; bumping it revokes
+-- them all, unlike a timestamp that JWT's second precision cannot resolve.
+ALTER TABLE users
+    ADD COLUMN is_active       BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN session_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX users_username_lower_idx ON users (LOWER(username));
@@ -0,0 +1,5 @@
+ALTER TABLE visualizations DROP COLUMN IF EXISTS org_id;
+ALTER TABLE templates DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,36 @@
+CREATE TABLE organizations (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name       VARCHAR(255) NOT NULL,
+    slug       VARCHAR(64)  NOT NULL UNIQUE,
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE organization_members (
+    org_id     UUID        NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role       VARCHAR(32) NOT NULL DEFAULT 'viewer',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX organization_members_user_id_idx ON organization_members (user_id);
+
+-- Move all existing data into a default organization.
+INSERT INTO organizations (name, slug) VALUES ('Default', 'default');
+
+INSERT INTO organization_members (org_id, user_id, role)
+SELECT o.id, u.id, u.role
+FROM users u
+CROSS JOIN organizations o
+WHERE o.slug = 'default';
+
+ALTER TABLE templates ADD COLUMN org_id UUID REFERENCES organizations (id);
+UPDATE templates SET org_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE templates ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX templates_org_id_idx ON templates (org_id);
+
+ALTER TABLE visualizations ADD COLUMN org_id UUID REFERENCES organizations (id);
+UPDATE visualizations SET org_id = (SELECT id FROM organizations WHERE slug = 'default');
+ALTER TABLE visualizations ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX visualizations_org_id_idx ON visualizations (org_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS invitations;
+
+DROP INDEX IF EXISTS users_email_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN email VARCHAR(255);
+CREATE UNIQUE INDEX users_email_idx ON users (LOWER(email));
+
+CREATE TABLE invitations (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id      UUID         NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email       VARCHAR(255) NOT NULL,
+    role        VARCHAR(32)  NOT NULL,
+    token_hash  CHAR(64)     NOT NULL UNIQUE,
+    invited_by  UUID REFERENCES users (id) ON DELETE SET NULL,
+    expires_at  TIMESTAMPTZ  NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    revoked_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX invitations_org_id_idx ON invitations (org_id);
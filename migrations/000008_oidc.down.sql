@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_oidc_identity_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS oidc_subject,
+    DROP COLUMN IF EXISTS oidc_issuer;
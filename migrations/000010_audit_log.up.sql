@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id      UUID,
+    actor_id    UUID,
+    actor_name  VARCHAR(64),
+    action      VARCHAR(64) NOT NULL,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id   VARCHAR(64),
+    before      JSONB,
+    after       JSONB,
+    ip          VARCHAR(45),
+    request_id  VARCHAR(128),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- No foreign keys on purpose: entries must outlive the users and
+-- organizations they mention.
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_org_id_idx ON audit_log (org_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_actor_id_idx ON audit_log (actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity_type, entity_id);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,14 @@
+ALTER TABLE visualizations ADD COLUMN IF NOT EXISTS share_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid();
+
+-- Restore the oldest active link of each visualization as its share ID.
+UPDATE visualizations v
+SET share_id = l.share_id
+FROM (
+    SELECT DISTINCT ON (visualization_id) visualization_id, share_id
+    FROM share_links
+    WHERE revoked_at IS NULL
+    ORDER BY visualization_id, created_at
+) l
+WHERE l.visualization_id = v.id;
+
+DROP TABLE IF EXISTS share_links;
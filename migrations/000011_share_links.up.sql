@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS share_links (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    visualization_id UUID         NOT NULL REFERENCES visualizations (id) ON DELETE CASCADE,
+    share_id         UUID         NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name             VARCHAR(100) NOT NULL,
+    password_hash    VARCHAR(255),
+    expires_at       TIMESTAMPTZ,
+    revoked_at       TIMESTAMPTZ,
+    view_count       INTEGER      NOT NULL DEFAULT 0,
+    viewed_at        TIMESTAMPTZ,
+    created_by       UUID REFERENCES users (id) ON DELETE SET NULL,
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS share_links_visualization_id_idx ON share_links (visualization_id);
+
+-- Keep every existing share URL working as a link named "Default".
+INSERT INTO share_links (visualization_id, share_id, name, created_by)
+SELECT id, share_id, 'Default', user_id
+FROM visualizations;
+
+ALTER TABLE visualizations DROP COLUMN IF EXISTS share_id;
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS visualization_reviews;
+
+ALTER TABLE visualizations
+    ADD COLUMN IF NOT EXISTS is_saved       BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS is_publishable BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE visualizations
+    DROP COLUMN IF EXISTS published_by,
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS published_snapshot,
+    DROP COLUMN IF EXISTS status;
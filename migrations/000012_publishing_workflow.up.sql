@@ -0,0 +1,36 @@
+ALTER TABLE visualizations
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'in_review', 'approved', 'published', 'archived')),
+    ADD COLUMN IF NOT EXISTS published_snapshot JSONB,
+    ADD COLUMN IF NOT EXISTS published_at       TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS published_by       UUID REFERENCES users (id) ON DELETE SET NULL;
+
+-- Freeze what is live today so viewers keep seeing the same content.
+UPDATE visualizations
+SET status             = 'published',
+    published_at       = updated_at,
+    published_snapshot = jsonb_build_object(
+        'name', name,
+        'description', description,
+        'client', client,
+        'canvases', canvases,
+        'template_id', template_id,
+        'tenant', tenant)
+WHERE is_published;
+
+ALTER TABLE visualizations
+    DROP COLUMN IF EXISTS is_saved,
+    DROP COLUMN IF EXISTS is_publishable;
+
+CREATE TABLE IF NOT EXISTS visualization_reviews (
+    visualization_id UUID        NOT NULL REFERENCES visualizations (id) ON DELETE CASCADE,
+    reviewer_id      UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    requested_by     UUID REFERENCES users (id) ON DELETE SET NULL,
+    decision         VARCHAR(16) CHECK (decision IN ('approved', 'rejected')),
+    comment          TEXT,
+    requested_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at       TIMESTAMPTZ,
+    PRIMARY KEY (visualization_id, reviewer_id)
+);
+
+CREATE INDEX IF NOT EXISTS visualization_reviews_reviewer_id_idx ON visualization_reviews (reviewer_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS visualizations_unpublish_at_idx;
+DROP INDEX IF EXISTS visualizations_publish_at_idx;
+
+ALTER TABLE visualizations
+    DROP CONSTRAINT IF EXISTS visualizations_schedule_order_chk,
+    DROP COLUMN IF EXISTS scheduled_by,
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at;
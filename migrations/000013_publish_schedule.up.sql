@@ -0,0 +1,10 @@
+ALTER TABLE visualizations
+    ADD COLUMN IF NOT EXISTS publish_at   TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS scheduled_by UUID REFERENCES users (id) ON DELETE SET NULL,
+    ADD CONSTRAINT visualizations_schedule_order_chk
+        CHECK (publish_at IS NULL OR unpublish_at IS NULL OR unpublish_at > publish_at);
+
+-- The scheduler polls for due rows; keep the scan limited to scheduled ones.
+CREATE INDEX IF NOT EXISTS visualizations_publish_at_idx ON visualizations (publish_at) WHERE publish_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS visualizations_unpublish_at_idx ON visualizations (unpublish_at) WHERE unpublish_at IS NOT NULL;
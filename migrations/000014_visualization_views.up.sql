@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS visualization_views (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    visualization_id UUID        NOT NULL REFERENCES visualizations (id) ON DELETE CASCADE,
+    share_link_id    UUID REFERENCES share_links (id) ON DELETE SET NULL,
+    viewer_hash      CHAR(64)    NOT NULL,
+    referrer         VARCHAR(255),
+    user_agent_class VARCHAR(16) NOT NULL,
+    viewed_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS visualization_views_viewed_at_idx ON visualization_views (visualization_id, viewed_at);
+-- Serves the deduplication lookup for a viewer's most recent view.
+CREATE INDEX IF NOT EXISTS visualization_views_viewer_idx ON visualization_views (visualization_id, viewer_hash, viewed_at DESC);
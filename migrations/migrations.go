@@ -0,0 +1,30 @@
+// Package migrations embeds the schema migrations so the application knows
+// which schema version it was built against.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.up.sql
+var files embed.FS
+
+// Latest returns the version of the newest migration, as recorded by
+// golang-migrate in schema_migrations once it is applied.
+func Latest() uint {
+	entries, _ := fs.ReadDir(files, ".")
+
+	var latest uint
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest
+}